@@ -0,0 +1,83 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mlkem768_test
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"filippo.io/mlkem768/internal/acvp"
+)
+
+func TestACVP(t *testing.T) {
+	dirs, err := filepath.Glob("testdata/acvp/ML-KEM-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dirs) == 0 {
+		t.Fatal("no ACVP vector sets found")
+	}
+	for _, dir := range dirs {
+		t.Run(filepath.Base(dir), func(t *testing.T) {
+			testACVPVectorSet(t, dir)
+		})
+	}
+}
+
+// TestACVPKeyCheck runs the encapsulationKeyCheck and decapsulationKeyCheck
+// functions of the ACVP runner on locally generated vectors, as the pinned NIST
+// vector sets don't include them. See testdata/keycheck/README.md.
+func TestACVPKeyCheck(t *testing.T) {
+	testACVPVectorSet(t, "testdata/keycheck")
+}
+
+func testACVPVectorSet(t *testing.T, dir string) {
+	var prompt acvp.Prompt
+	readGzipJSON(t, filepath.Join(dir, "prompt.json.gz"), &prompt)
+	var expected acvp.Response
+	readGzipJSON(t, filepath.Join(dir, "expectedResults.json.gz"), &expected)
+
+	got, err := acvp.Respond(&prompt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.TestGroups) != len(expected.TestGroups) {
+		t.Fatalf("got %d test groups, expected %d", len(got.TestGroups), len(expected.TestGroups))
+	}
+	var n int
+	for i, g := range got.TestGroups {
+		eg := expected.TestGroups[i]
+		if g.TgID != eg.TgID || len(g.Tests) != len(eg.Tests) {
+			t.Fatalf("test group %d doesn't match expected test group %d", g.TgID, eg.TgID)
+		}
+		for j, tc := range g.Tests {
+			if !reflect.DeepEqual(tc, eg.Tests[j]) {
+				t.Errorf("tgId %d, tcId %d: result doesn't match", g.TgID, tc.TcID)
+			}
+			n++
+		}
+	}
+	t.Logf("%d test cases passed", n)
+}
+
+func readGzipJSON(t *testing.T, name string, v any) {
+	t.Helper()
+	f, err := os.Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	r, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.NewDecoder(r).Decode(v); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,67 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Command mlkem768-acvp produces an ACVP response for an ML-KEM prompt file.
+//
+// Usage:
+//
+//	mlkem768-acvp [prompt.json] > response.json
+//
+// If no file is given, the prompt is read from standard input. Only the
+// ML-KEM-768 test groups are processed, and the others are omitted from the
+// response. The response has the same format as the expectedResults files
+// published by NIST, and requires no network access to produce.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"filippo.io/mlkem768/internal/acvp"
+)
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("mlkem768-acvp: ")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: mlkem768-acvp [prompt.json] > response.json\n")
+	}
+	flag.Parse()
+
+	var in io.Reader = os.Stdin
+	switch flag.NArg() {
+	case 0:
+	case 1:
+		f, err := os.Open(flag.Arg(0))
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+		in = f
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	var prompt acvp.Prompt
+	if err := json.NewDecoder(in).Decode(&prompt); err != nil {
+		log.Fatalf("failed to parse prompt: %v", err)
+	}
+	response, err := acvp.Respond(&prompt)
+	if err != nil {
+		log.Fatal(err)
+	}
+	out, err := json.MarshalIndent(response, "", "  ")
+	if err != nil {
+		log.Fatal(err)
+	}
+	out = append(out, '\n')
+	if _, err := os.Stdout.Write(out); err != nil {
+		log.Fatal(err)
+	}
+}
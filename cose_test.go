@@ -0,0 +1,275 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mlkem768_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	. "filippo.io/mlkem768"
+)
+
+func TestCOSEKey(t *testing.T) {
+	seed := testSeed()
+	dk, err := NewKeyFromSeed(seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ek, err := NewEncapsulationKey(dk.EncapsulationKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// {1: 7, 3: -65537, -1: pub, -2: priv}
+	header := []byte{0x01, 0x07, 0x03, 0x3a, 0x00, 0x01, 0x00, 0x00}
+	wantPub := append([]byte{0xa3}, header...)
+	wantPub = append(wantPub, 0x20, 0x59, 0x04, 0xa0)
+	wantPub = append(wantPub, ek.Bytes()...)
+	wantPriv := append([]byte{0xa4}, wantPub[1:]...)
+	wantPriv = append(wantPriv, 0x21, 0x58, 0x40)
+	wantPriv = append(wantPriv, seed...)
+
+	priv, err := MarshalCOSEPrivateKey(dk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(priv, wantPriv) {
+		t.Errorf("MarshalCOSEPrivateKey: got %x, expected %x", priv, wantPriv)
+	}
+	dk1, err := ParseCOSEPrivateKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(dk1.Bytes(), seed) {
+		t.Errorf("ParseCOSEPrivateKey: got %x, expected %x", dk1.Bytes(), seed)
+	}
+
+	pub, err := MarshalCOSEPublicKey(ek)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(pub, wantPub) {
+		t.Errorf("MarshalCOSEPublicKey: got %x, expected %x", pub, wantPub)
+	}
+	ek1, err := ParseCOSEPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(ek1.Bytes(), ek.Bytes()) {
+		t.Errorf("ParseCOSEPublicKey: got %x, expected %x", ek1.Bytes(), ek.Bytes())
+	}
+	if _, err := ParseCOSEPublicKey(priv); err == nil {
+		t.Errorf("ParseCOSEPublicKey: expected error for private key")
+	}
+	if _, err := ParseCOSEPrivateKey(pub); err == nil {
+		t.Errorf("ParseCOSEPrivateKey: expected error for public key")
+	}
+
+	// Map keys in a different order are accepted, and unknown labels ignored.
+	reordered := []byte{0xa5, 0x21, 0x58, 0x40}
+	reordered = append(reordered, seed...)
+	reordered = append(reordered, 0x02, 0x41, 0x00) // kid
+	reordered = append(reordered, wantPub[1:]...)
+	if dk1, err := ParseCOSEPrivateKey(reordered); err != nil {
+		t.Errorf("ParseCOSEPrivateKey: reordered key: %v", err)
+	} else if !bytes.Equal(dk1.Bytes(), seed) {
+		t.Errorf("ParseCOSEPrivateKey: got %x, expected %x", dk1.Bytes(), seed)
+	}
+
+	otherPriv := bytes.Clone(priv)
+	otherPriv[len(otherPriv)-SeedSize-1] ^= 1
+	for name, data := range map[string][]byte{
+		"kty":          replace(priv, header[:2], []byte{0x01, 0x01}),
+		"alg":          replace(priv, header[2:], []byte{0x03, 0x3a, 0x00, 0x01, 0x00, 0x01}),
+		"non-minimal":  replace(priv, header[:2], []byte{0x01, 0x18, 0x07}),
+		"duplicate":    append(replace(priv, []byte{0xa4}, []byte{0xa5}), 0x01, 0x07),
+		"trailing":     append(bytes.Clone(priv), 0x00),
+		"truncated":    priv[:len(priv)-1],
+		"missing pub":  append(append([]byte{0xa3}, header...), priv[len(priv)-3-SeedSize:]...),
+		"mismatch pub": otherPriv,
+		"short priv":   append(replace(priv[:len(priv)-SeedSize], []byte{0x58, 0x40}, []byte{0x58, 0x3f}), seed[1:]...),
+		"indefinite":   replace(priv, []byte{0xa4}, []byte{0xbf}),
+	} {
+		if _, err := ParseCOSEPrivateKey(data); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+
+	dkx, err := NewKeyFromExpanded(dk.ExpandedBytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := MarshalCOSEPrivateKey(dkx); err == nil {
+		t.Errorf("MarshalCOSEPrivateKey: expected error for expanded key")
+	}
+	dk.Destroy()
+	if _, err := MarshalCOSEPrivateKey(dk); !errors.Is(err, ErrKeyDestroyed) {
+		t.Errorf("MarshalCOSEPrivateKey: got %v, expected %v", err, ErrKeyDestroyed)
+	}
+}
+
+func replace(b, old, new []byte) []byte {
+	return bytes.Replace(b, old, new, 1)
+}
+
+func TestCOSEEncrypt(t *testing.T) {
+	dk, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ek, err := NewEncapsulationKey(dk.EncapsulationKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+	plaintext := []byte("the quick brown fox")
+	aad := []byte("external")
+
+	for _, kid := range [][]byte{nil, []byte("key-1")} {
+		msg, err := SealCOSEEncrypt(ek, kid, plaintext, aad)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Tag 96, then a four-element array.
+		if !bytes.HasPrefix(msg, []byte{0xd8, 0x60, 0x84}) {
+			t.Errorf("SealCOSEEncrypt: unexpected prefix %x", msg[:3])
+		}
+		if kid != nil && !bytes.Contains(msg, kid) {
+			t.Errorf("SealCOSEEncrypt: kid not found in message")
+		}
+		got, err := OpenCOSEEncrypt(dk, msg, aad)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, plaintext) {
+			t.Errorf("OpenCOSEEncrypt: got %q, expected %q", got, plaintext)
+		}
+
+		if _, err := OpenCOSEEncrypt(dk, msg, []byte("other")); err == nil {
+			t.Errorf("OpenCOSEEncrypt: expected error for wrong external AAD")
+		}
+		other, err := GenerateKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := OpenCOSEEncrypt(other, msg, aad); err == nil {
+			t.Errorf("OpenCOSEEncrypt: expected error for wrong key")
+		}
+		// The kid is an unauthenticated hint, but without it every byte matters.
+		for i := range msg {
+			if kid != nil {
+				break
+			}
+			tampered := bytes.Clone(msg)
+			tampered[i] ^= 0x01
+			if got, err := OpenCOSEEncrypt(dk, tampered, aad); err == nil {
+				t.Errorf("OpenCOSEEncrypt: byte %d: tampered message decrypted to %q", i, got)
+			}
+		}
+		if _, err := OpenCOSEEncrypt(dk, msg[:len(msg)-1], aad); err == nil {
+			t.Errorf("OpenCOSEEncrypt: expected error for truncated message")
+		}
+	}
+
+	msg, err := SealCOSEEncrypt(ek, nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := OpenCOSEEncrypt(dk, msg, nil); err != nil || len(got) != 0 {
+		t.Errorf("OpenCOSEEncrypt: empty plaintext: got %q, %v", got, err)
+	}
+	dk.Destroy()
+	if _, err := OpenCOSEEncrypt(dk, msg, nil); !errors.Is(err, ErrKeyDestroyed) {
+		t.Errorf("OpenCOSEEncrypt: got %v, expected %v", err, ErrKeyDestroyed)
+	}
+}
+
+// FuzzParseCOSEKey checks that ParseCOSEPrivateKey and ParseCOSEPublicKey
+// don't panic, never both accept the same input, and that the keys they
+// return marshal to an equivalent COSE_Key.
+func FuzzParseCOSEKey(f *testing.F) {
+	dk, err := NewKeyFromSeed(testSeed())
+	if err != nil {
+		f.Fatal(err)
+	}
+	ek, err := NewEncapsulationKey(dk.EncapsulationKey())
+	if err != nil {
+		f.Fatal(err)
+	}
+	priv, err := MarshalCOSEPrivateKey(dk)
+	if err != nil {
+		f.Fatal(err)
+	}
+	pub, err := MarshalCOSEPublicKey(ek)
+	if err != nil {
+		f.Fatal(err)
+	}
+	f.Add(priv)
+	f.Add(pub)
+	f.Add(priv[:len(priv)-1])
+	f.Add(pub[:20])
+	f.Fuzz(func(t *testing.T, data []byte) {
+		dk, privErr := ParseCOSEPrivateKey(data)
+		ek, pubErr := ParseCOSEPublicKey(data)
+		if privErr == nil && pubErr == nil {
+			t.Fatal("input parsed as both a private and a public key")
+		}
+		if privErr == nil {
+			out, err := MarshalCOSEPrivateKey(dk)
+			if err != nil {
+				t.Fatal(err)
+			}
+			dk1, err := ParseCOSEPrivateKey(out)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(dk1.Bytes(), dk.Bytes()) {
+				t.Errorf("round trip: got %x, expected %x", dk1.Bytes(), dk.Bytes())
+			}
+		}
+		if pubErr == nil {
+			out, err := MarshalCOSEPublicKey(ek)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ek1, err := ParseCOSEPublicKey(out)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(ek1.Bytes(), ek.Bytes()) {
+				t.Errorf("round trip: got %x, expected %x", ek1.Bytes(), ek.Bytes())
+			}
+		}
+	})
+}
+
+// FuzzOpenCOSEEncrypt checks that OpenCOSEEncrypt doesn't panic on arbitrary
+// messages, and that any message it accepts decrypts to the only plaintext
+// that was ever encrypted to the key.
+func FuzzOpenCOSEEncrypt(f *testing.F) {
+	dk, err := NewKeyFromSeed(testSeed())
+	if err != nil {
+		f.Fatal(err)
+	}
+	ek, err := NewEncapsulationKey(dk.EncapsulationKey())
+	if err != nil {
+		f.Fatal(err)
+	}
+	plaintext, aad := []byte("the quick brown fox"), []byte("external")
+	for _, kid := range [][]byte{nil, []byte("key-1")} {
+		msg, err := SealCOSEEncrypt(ek, kid, plaintext, aad)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(msg)
+		f.Add(msg[:len(msg)-1])
+	}
+	f.Fuzz(func(t *testing.T, msg []byte) {
+		got, err := OpenCOSEEncrypt(dk, msg, aad)
+		if err == nil && !bytes.Equal(got, plaintext) {
+			t.Errorf("got plaintext %q, expected %q", got, plaintext)
+		}
+	})
+}
@@ -0,0 +1,133 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mlkem768
+
+import (
+	"fmt"
+
+	"filippo.io/mlkem768/internal/keytext"
+)
+
+// AppendBinary implements [encoding.BinaryAppender]. It appends the 64-byte
+// seed returned by [DecapsulationKey.Bytes], or if dk was created by
+// [NewKeyFromExpanded], the 2400-byte expanded form returned by
+// [DecapsulationKey.ExpandedBytes].
+func (dk *DecapsulationKey) AppendBinary(b []byte) ([]byte, error) {
+	if dk.destroyed {
+		return nil, ErrKeyDestroyed
+	}
+	if !dk.hasSeed() {
+		return append(b, dk.ExpandedBytes()...), nil
+	}
+	return append(b, dk.Bytes()...), nil
+}
+
+// MarshalBinary implements [encoding.BinaryMarshaler]. It returns the same
+// encoding as [DecapsulationKey.AppendBinary].
+func (dk *DecapsulationKey) MarshalBinary() ([]byte, error) {
+	return dk.AppendBinary(nil)
+}
+
+// UnmarshalBinary implements [encoding.BinaryUnmarshaler]. It replaces dk with
+// the key parsed from a 64-byte seed, like [NewKeyFromSeed], or from a 2400-byte
+// expanded key, like [NewKeyFromExpanded]. If data is invalid, dk is unchanged.
+func (dk *DecapsulationKey) UnmarshalBinary(data []byte) error {
+	var k *DecapsulationKey
+	var err error
+	switch len(data) {
+	case SeedSize:
+		k, err = NewKeyFromSeed(data)
+	case ExpandedDecapsulationKeySize:
+		k, err = NewKeyFromExpanded(data)
+	default:
+		return fmt.Errorf("%w: %w", ErrInvalidDecapsulationKey, ErrKeyLength)
+	}
+	if err != nil {
+		return err
+	}
+	dk.Destroy()
+	*dk = *k
+	*k = DecapsulationKey{}
+	return nil
+}
+
+// MarshalText implements [encoding.TextMarshaler]. It returns the output of
+// [DecapsulationKey.MarshalBinary], base64 encoded.
+//
+// Note that the output is the secret key, in a format suitable for storage.
+func (dk *DecapsulationKey) MarshalText() ([]byte, error) {
+	b, err := dk.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	return keytext.Encode(b), nil
+}
+
+// UnmarshalText implements [encoding.TextUnmarshaler]. It decodes the base64
+// output of [DecapsulationKey.MarshalText], and parses it like
+// [DecapsulationKey.UnmarshalBinary].
+func (dk *DecapsulationKey) UnmarshalText(text []byte) error {
+	b, err := keytext.Decode(text, ErrInvalidDecapsulationKey)
+	if err != nil {
+		return err
+	}
+	return dk.UnmarshalBinary(b)
+}
+
+// String returns a placeholder, to avoid leaking the secret key in logs.
+func (dk *DecapsulationKey) String() string {
+	return keytext.Redacted("mlkem768.DecapsulationKey")
+}
+
+// GoString returns the same placeholder as [DecapsulationKey.String].
+func (dk *DecapsulationKey) GoString() string {
+	return dk.String()
+}
+
+// Format implements [fmt.Formatter], printing the same placeholder as
+// [DecapsulationKey.String] for every verb, including %x and %#v.
+func (dk *DecapsulationKey) Format(f fmt.State, verb rune) {
+	fmt.Fprint(f, dk.String())
+}
+
+// AppendBinary implements [encoding.BinaryAppender]. It appends the encoded
+// encapsulation key returned by [EncapsulationKey.Bytes].
+func (ek *EncapsulationKey) AppendBinary(b []byte) ([]byte, error) {
+	return append(b, ek.Bytes()...), nil
+}
+
+// MarshalBinary implements [encoding.BinaryMarshaler]. It returns the encoded
+// encapsulation key returned by [EncapsulationKey.Bytes].
+func (ek *EncapsulationKey) MarshalBinary() ([]byte, error) {
+	return ek.AppendBinary(nil)
+}
+
+// UnmarshalBinary implements [encoding.BinaryUnmarshaler]. It replaces ek with
+// the key parsed by [NewEncapsulationKey]. If data is invalid, ek is unchanged.
+func (ek *EncapsulationKey) UnmarshalBinary(data []byte) error {
+	k, err := NewEncapsulationKey(data)
+	if err != nil {
+		return err
+	}
+	*ek = *k
+	return nil
+}
+
+// MarshalText implements [encoding.TextMarshaler]. It returns the encoded
+// encapsulation key, base64 encoded.
+func (ek *EncapsulationKey) MarshalText() ([]byte, error) {
+	return keytext.Encode(ek.Bytes()), nil
+}
+
+// UnmarshalText implements [encoding.TextUnmarshaler]. It decodes the base64
+// output of [EncapsulationKey.MarshalText], and parses it like
+// [EncapsulationKey.UnmarshalBinary].
+func (ek *EncapsulationKey) UnmarshalText(text []byte) error {
+	b, err := keytext.Decode(text, ErrInvalidEncapsulationKey)
+	if err != nil {
+		return err
+	}
+	return ek.UnmarshalBinary(b)
+}
@@ -0,0 +1,229 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mlkem768_test
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	. "filippo.io/mlkem768"
+)
+
+var (
+	_ encoding.BinaryAppender    = (*DecapsulationKey)(nil)
+	_ encoding.BinaryMarshaler   = (*DecapsulationKey)(nil)
+	_ encoding.BinaryUnmarshaler = (*DecapsulationKey)(nil)
+	_ encoding.TextMarshaler     = (*DecapsulationKey)(nil)
+	_ encoding.TextUnmarshaler   = (*DecapsulationKey)(nil)
+	_ fmt.Formatter              = (*DecapsulationKey)(nil)
+	_ fmt.Stringer               = (*DecapsulationKey)(nil)
+	_ fmt.GoStringer             = (*DecapsulationKey)(nil)
+	_ encoding.BinaryAppender    = (*EncapsulationKey)(nil)
+	_ encoding.BinaryMarshaler   = (*EncapsulationKey)(nil)
+	_ encoding.BinaryUnmarshaler = (*EncapsulationKey)(nil)
+	_ encoding.TextMarshaler     = (*EncapsulationKey)(nil)
+	_ encoding.TextUnmarshaler   = (*EncapsulationKey)(nil)
+)
+
+func TestBinaryEncoding(t *testing.T) {
+	seed := testSeed()
+	dk, err := NewKeyFromSeed(seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := dk.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b, seed) {
+		t.Errorf("MarshalBinary: got %x, expected %x", b, seed)
+	}
+	prefix := []byte("prefix")
+	b, err = dk.AppendBinary(bytes.Clone(prefix))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b, append(prefix, seed...)) {
+		t.Errorf("AppendBinary: got %x, expected %x", b, append(prefix, seed...))
+	}
+	var dk1 DecapsulationKey
+	if err := dk1.UnmarshalBinary(seed); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(dk1.EncapsulationKey(), dk.EncapsulationKey()) {
+		t.Errorf("UnmarshalBinary: got a different key")
+	}
+
+	// Keys without a seed use the expanded form.
+	dkx, err := NewKeyFromExpanded(dk.ExpandedBytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err = dkx.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b, dk.ExpandedBytes()) {
+		t.Errorf("MarshalBinary: got %x, expected expanded key", b)
+	}
+	if err := dk1.UnmarshalBinary(b); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(dk1.ExpandedBytes(), dk.ExpandedBytes()) {
+		t.Errorf("UnmarshalBinary: got a different expanded key")
+	}
+
+	// Invalid inputs leave the key unchanged.
+	if err := dk1.UnmarshalBinary(seed[1:]); !errors.Is(err, ErrInvalidDecapsulationKey) || !errors.Is(err, ErrKeyLength) {
+		t.Errorf("UnmarshalBinary: got %v, expected %v and %v", err, ErrInvalidDecapsulationKey, ErrKeyLength)
+	}
+	badExpanded := dk.ExpandedBytes()
+	badExpanded[len(badExpanded)-SeedSize-1] ^= 1
+	if err := dk1.UnmarshalBinary(badExpanded); !errors.Is(err, ErrKeyHash) {
+		t.Errorf("UnmarshalBinary: got %v, expected %v", err, ErrKeyHash)
+	}
+	if !bytes.Equal(dk1.ExpandedBytes(), dk.ExpandedBytes()) {
+		t.Errorf("UnmarshalBinary: key changed after error")
+	}
+
+	ek, err := NewEncapsulationKey(dk.EncapsulationKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err = ek.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b, ek.Bytes()) {
+		t.Errorf("MarshalBinary: got %x, expected %x", b, ek.Bytes())
+	}
+	b, err = ek.AppendBinary(bytes.Clone(prefix))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b, append(prefix, ek.Bytes()...)) {
+		t.Errorf("AppendBinary: got %x, expected %x", b, append(prefix, ek.Bytes()...))
+	}
+	var ek1 EncapsulationKey
+	if err := ek1.UnmarshalBinary(ek.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(ek1.Bytes(), ek.Bytes()) {
+		t.Errorf("UnmarshalBinary: got %x, expected %x", ek1.Bytes(), ek.Bytes())
+	}
+	if err := ek1.UnmarshalBinary(ek.Bytes()[1:]); !errors.Is(err, ErrInvalidEncapsulationKey) {
+		t.Errorf("UnmarshalBinary: got %v, expected %v", err, ErrInvalidEncapsulationKey)
+	}
+	if !bytes.Equal(ek1.Bytes(), ek.Bytes()) {
+		t.Errorf("UnmarshalBinary: key changed after error")
+	}
+
+	dk.Destroy()
+	if _, err := dk.MarshalBinary(); !errors.Is(err, ErrKeyDestroyed) {
+		t.Errorf("MarshalBinary: got %v, expected %v", err, ErrKeyDestroyed)
+	}
+	if _, err := dk.MarshalText(); !errors.Is(err, ErrKeyDestroyed) {
+		t.Errorf("MarshalText: got %v, expected %v", err, ErrKeyDestroyed)
+	}
+}
+
+func TestTextEncoding(t *testing.T) {
+	seed := testSeed()
+	dk, err := NewKeyFromSeed(seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ek, err := NewEncapsulationKey(dk.EncapsulationKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type config struct {
+		DK *DecapsulationKey
+		EK *EncapsulationKey
+	}
+	data, err := json.Marshal(config{DK: dk, EK: ek})
+	if err != nil {
+		t.Fatal(err)
+	}
+	seedText := base64.StdEncoding.EncodeToString(seed)
+	want := `{"DK":"` + seedText + `","EK":"` + base64.StdEncoding.EncodeToString(ek.Bytes()) + `"}`
+	if string(data) != want {
+		t.Errorf("json.Marshal: got %s, expected %s", data, want)
+	}
+	var c config
+	if err := json.Unmarshal(data, &c); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(c.DK.Bytes(), seed) {
+		t.Errorf("json.Unmarshal: got a different decapsulation key")
+	}
+	if !bytes.Equal(c.EK.Bytes(), ek.Bytes()) {
+		t.Errorf("json.Unmarshal: got a different encapsulation key")
+	}
+
+	for _, text := range []string{
+		base64.RawStdEncoding.EncodeToString(seed),
+		base64.URLEncoding.EncodeToString(seed),
+		base64.StdEncoding.EncodeToString(seed[1:]),
+		"!" + seedText,
+	} {
+		var dk1 DecapsulationKey
+		if err := dk1.UnmarshalText([]byte(text)); !errors.Is(err, ErrInvalidDecapsulationKey) {
+			t.Errorf("UnmarshalText(%q): got %v, expected %v", text, err, ErrInvalidDecapsulationKey)
+		}
+	}
+	var ek1 EncapsulationKey
+	if err := ek1.UnmarshalText([]byte(seedText)); !errors.Is(err, ErrInvalidEncapsulationKey) {
+		t.Errorf("UnmarshalText: got %v, expected %v", err, ErrInvalidEncapsulationKey)
+	}
+}
+
+func TestRedaction(t *testing.T) {
+	seed := testSeed()
+	dk, err := NewKeyFromSeed(seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	secrets := []string{
+		hex.EncodeToString(seed),
+		strings.ToUpper(hex.EncodeToString(seed)),
+		hex.EncodeToString(seed[:8]),
+		strings.Trim(fmt.Sprint(seed[:8]), "[]"),
+		base64.StdEncoding.EncodeToString(seed),
+	}
+	// Only pointers are redacted, as keys should not be held by value.
+	type wrapper struct {
+		Ptr *DecapsulationKey
+	}
+	for _, format := range []string{"%v", "%+v", "%#v", "%s", "%q", "%x", "%X", "%d", "%T"} {
+		for _, arg := range []any{dk, wrapper{Ptr: dk}, &wrapper{Ptr: dk}, []*DecapsulationKey{dk},
+			map[string]*DecapsulationKey{"k": dk}} {
+			out := fmt.Sprintf(format, arg)
+			for _, s := range secrets {
+				if strings.Contains(out, s) {
+					t.Errorf("Sprintf(%q, %T) leaked the seed: %s", format, arg, out)
+				}
+			}
+		}
+	}
+	if got := fmt.Sprint(dk); got != "mlkem768.DecapsulationKey(REDACTED)" {
+		t.Errorf("Sprint: got %q", got)
+	}
+	if got := fmt.Sprintf("%#v", dk); got != "mlkem768.DecapsulationKey(REDACTED)" {
+		t.Errorf("Sprintf(%%#v): got %q", got)
+	}
+	dk.Destroy()
+	if got := dk.String(); got != "mlkem768.DecapsulationKey(REDACTED)" {
+		t.Errorf("String after Destroy: got %q", got)
+	}
+}
@@ -0,0 +1,64 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mlkem768
+
+import (
+	"errors"
+	"fmt"
+
+	"filippo.io/mlkem768/internal/mlkem"
+)
+
+// Errors returned by this package, for use with [errors.Is].
+//
+// Errors about invalid keys match either [ErrInvalidEncapsulationKey] or
+// [ErrInvalidDecapsulationKey], and the more specific [ErrKeyLength],
+// [ErrKeyModulus], or [ErrKeyHash].
+var (
+	ErrInvalidSeed             = errors.New("mlkem768: invalid seed length")
+	ErrInvalidRandomness       = errors.New("mlkem768: invalid randomness length")
+	ErrInvalidCiphertextLength = errors.New("mlkem768: invalid ciphertext length")
+	ErrInvalidEncapsulationKey = errors.New("mlkem768: invalid encapsulation key")
+	ErrInvalidDecapsulationKey = errors.New("mlkem768: invalid decapsulation key")
+	ErrKeyDestroyed            = errors.New("mlkem768: use of destroyed DecapsulationKey")
+
+	// ErrPairwiseConsistency is returned by GenerateKeyWithPCT if the
+	// generated key fails the pairwise consistency test.
+	ErrPairwiseConsistency = errors.New("mlkem768: pairwise consistency test failed")
+
+	// ErrSelfTest is returned by SelfTest if the implementation produces
+	// unexpected results.
+	ErrSelfTest = errors.New("mlkem768: self-test failed")
+
+	// ErrKeyLength is returned for keys of the wrong length.
+	ErrKeyLength = mlkem.ErrKeyLength
+
+	// ErrKeyModulus is returned for keys with coefficients that are not
+	// reduced modulo q.
+	ErrKeyModulus = mlkem.ErrKeyModulus
+
+	// ErrKeyHash is returned for expanded decapsulation keys where the
+	// embedded H(ek) doesn't match the embedded encapsulation key.
+	ErrKeyHash = mlkem.ErrKeyHash
+)
+
+// encapsulationKeyError returns an error that wraps ErrInvalidEncapsulationKey
+// and the reason ek was rejected. err is the error returned by the underlying
+// implementation, which is used if the reason can't be determined.
+func encapsulationKeyError(ek []byte, err error) error {
+	if reason := mlkem.CheckEncapsulationKey(mlkem.MLKEM768, ek); reason != nil {
+		err = reason
+	}
+	return fmt.Errorf("%w: %w", ErrInvalidEncapsulationKey, err)
+}
+
+// decapsulationKeyError is like encapsulationKeyError, but for expanded
+// decapsulation keys.
+func decapsulationKeyError(expanded []byte, err error) error {
+	if reason := mlkem.CheckExpandedDecapsulationKey(mlkem.MLKEM768, expanded); reason != nil {
+		err = reason
+	}
+	return fmt.Errorf("%w: %w", ErrInvalidDecapsulationKey, err)
+}
@@ -0,0 +1,15 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !go1.26
+
+package mlkem768
+
+import "unsafe"
+
+// KeyMemory returns the memory backing the key material of dk, so that tests
+// can check that Destroy overwrites it in place.
+func KeyMemory(dk *DecapsulationKey) []byte {
+	return unsafe.Slice((*byte)(unsafe.Pointer(&dk.k)), unsafe.Sizeof(dk.k))
+}
@@ -0,0 +1,23 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build go1.26
+
+package mlkem768
+
+import (
+	"unsafe"
+
+	internalmlkem "filippo.io/mlkem768/internal/mlkem"
+)
+
+// KeyMemory returns the memory backing the key material of dk, so that tests
+// can check that Destroy overwrites it in place. It returns nil if the key
+// material is held by crypto/mlkem, which doesn't support zeroization.
+func KeyMemory(dk *DecapsulationKey) []byte {
+	if dk.x == nil {
+		return nil
+	}
+	return unsafe.Slice((*byte)(unsafe.Pointer(dk.x)), unsafe.Sizeof(internalmlkem.DecapsulationKey{}))
+}
@@ -0,0 +1,12 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mlkem768
+
+// SetPCTFault sets the fault injection hook of the pairwise consistency test,
+// returning a function that removes it.
+func SetPCTFault(f func(sharedKey []byte)) (restore func()) {
+	pctFault = f
+	return func() { pctFault = nil }
+}
@@ -0,0 +1,179 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package acvp implements the ML-KEM-768 subset of the NIST ACVP ML-KEM JSON
+// protocol, as specified in [draft-celi-acvp-ml-kem], on top of the mlkem768
+// package.
+//
+// It supports the keyGen mode, and the encapDecap mode with the encapsulation,
+// decapsulation, encapsulationKeyCheck, and decapsulationKeyCheck functions.
+//
+// [draft-celi-acvp-ml-kem]: https://pages.nist.gov/ACVP/draft-celi-acvp-ml-kem.html
+package acvp
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"filippo.io/mlkem768"
+)
+
+// ParameterSet is the only ACVP parameter set supported by this package.
+// Test groups for other parameter sets are skipped.
+const ParameterSet = "ML-KEM-768"
+
+// HexBytes is a byte slice that is encoded as uppercase hex in JSON, like in
+// ACVP files.
+type HexBytes []byte
+
+func (b HexBytes) MarshalText() ([]byte, error) {
+	return []byte(strings.ToUpper(hex.EncodeToString(b))), nil
+}
+
+func (b *HexBytes) UnmarshalText(text []byte) error {
+	out, err := hex.DecodeString(string(text))
+	if err != nil {
+		return err
+	}
+	*b = out
+	return nil
+}
+
+// Prompt is an ACVP prompt file, containing the inputs of a vector set.
+type Prompt struct {
+	VsID       int           `json:"vsId"`
+	Algorithm  string        `json:"algorithm"`
+	Mode       string        `json:"mode"`
+	Revision   string        `json:"revision"`
+	IsSample   bool          `json:"isSample"`
+	TestGroups []PromptGroup `json:"testGroups"`
+}
+
+// PromptGroup is a test group of a [Prompt].
+type PromptGroup struct {
+	TgID         int          `json:"tgId"`
+	TestType     string       `json:"testType"`
+	ParameterSet string       `json:"parameterSet"`
+	Function     string       `json:"function,omitempty"`
+	DK           HexBytes     `json:"dk,omitempty"`
+	Tests        []PromptTest `json:"tests"`
+}
+
+// PromptTest is a test case of a [PromptGroup].
+type PromptTest struct {
+	TcID int      `json:"tcId"`
+	D    HexBytes `json:"d,omitempty"`
+	Z    HexBytes `json:"z,omitempty"`
+	EK   HexBytes `json:"ek,omitempty"`
+	DK   HexBytes `json:"dk,omitempty"`
+	M    HexBytes `json:"m,omitempty"`
+	C    HexBytes `json:"c,omitempty"`
+}
+
+// Response is an ACVP response file, containing the results of a vector set.
+// It has the same format as the expectedResults files.
+type Response struct {
+	VsID       int             `json:"vsId"`
+	Algorithm  string          `json:"algorithm"`
+	Mode       string          `json:"mode"`
+	Revision   string          `json:"revision"`
+	IsSample   bool            `json:"isSample"`
+	TestGroups []ResponseGroup `json:"testGroups"`
+}
+
+// ResponseGroup is a test group of a [Response].
+type ResponseGroup struct {
+	TgID  int            `json:"tgId"`
+	Tests []ResponseTest `json:"tests"`
+}
+
+// ResponseTest is a test case of a [ResponseGroup].
+type ResponseTest struct {
+	TcID       int      `json:"tcId"`
+	EK         HexBytes `json:"ek,omitempty"`
+	DK         HexBytes `json:"dk,omitempty"`
+	C          HexBytes `json:"c,omitempty"`
+	K          HexBytes `json:"k,omitempty"`
+	TestPassed *bool    `json:"testPassed,omitempty"`
+}
+
+// Respond runs the test cases of p, and returns the corresponding response.
+// Test groups for parameter sets other than [ParameterSet] are skipped.
+func Respond(p *Prompt) (*Response, error) {
+	if p.Algorithm != "ML-KEM" || p.Revision != "FIPS203" {
+		return nil, fmt.Errorf("acvp: unsupported algorithm %q revision %q", p.Algorithm, p.Revision)
+	}
+	r := &Response{
+		VsID:      p.VsID,
+		Algorithm: p.Algorithm,
+		Mode:      p.Mode,
+		Revision:  p.Revision,
+		IsSample:  p.IsSample,
+	}
+	for _, g := range p.TestGroups {
+		if g.ParameterSet != ParameterSet {
+			continue
+		}
+		rg := ResponseGroup{TgID: g.TgID}
+		for _, t := range g.Tests {
+			rt, err := respondTest(p.Mode, &g, &t)
+			if err != nil {
+				return nil, fmt.Errorf("acvp: tgId %d, tcId %d: %w", g.TgID, t.TcID, err)
+			}
+			rg.Tests = append(rg.Tests, *rt)
+		}
+		r.TestGroups = append(r.TestGroups, rg)
+	}
+	return r, nil
+}
+
+func respondTest(mode string, g *PromptGroup, t *PromptTest) (*ResponseTest, error) {
+	rt := &ResponseTest{TcID: t.TcID}
+	switch {
+	case mode == "keyGen":
+		dk, err := mlkem768.NewKeyFromSeed(append(bytes.Clone(t.D), t.Z...))
+		if err != nil {
+			return nil, err
+		}
+		rt.EK = dk.EncapsulationKey()
+		rt.DK = dk.ExpandedBytes()
+
+	case mode == "encapDecap" && g.Function == "encapsulation":
+		c, k, err := mlkem768.EncapsulateDerand(t.EK, t.M)
+		if err != nil {
+			return nil, err
+		}
+		rt.C, rt.K = c, k
+
+	case mode == "encapDecap" && g.Function == "decapsulation":
+		dkBytes := g.DK
+		if t.DK != nil {
+			dkBytes = t.DK
+		}
+		dk, err := mlkem768.NewKeyFromExpanded(dkBytes)
+		if err != nil {
+			return nil, err
+		}
+		k, err := mlkem768.Decapsulate(dk, t.C)
+		if err != nil {
+			return nil, err
+		}
+		rt.K = k
+
+	case mode == "encapDecap" && g.Function == "encapsulationKeyCheck":
+		passed := mlkem768.ValidateEncapsulationKey(t.EK) == nil
+		rt.TestPassed = &passed
+
+	case mode == "encapDecap" && g.Function == "decapsulationKeyCheck":
+		passed := mlkem768.ValidateDecapsulationKey(t.DK) == nil
+		rt.TestPassed = &passed
+
+	default:
+		return nil, errors.New("unsupported mode " + mode + " function " + g.Function)
+	}
+	return rt, nil
+}
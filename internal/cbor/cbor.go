@@ -0,0 +1,321 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package cbor implements the small subset of CBOR (RFC 8949) needed by COSE
+// keys and messages: integers, byte and text strings, arrays, maps, tags, and
+// null, all with definite lengths.
+//
+// The encoder produces the preferred (shortest) serialization, and the decoder
+// rejects any other, as well as indefinite lengths and other unsupported types.
+// Map keys are not sorted or deduplicated, which is left to the caller.
+package cbor
+
+import (
+	"errors"
+	"math"
+)
+
+// CBOR major types.
+const (
+	majorUint   = 0
+	majorNegInt = 1
+	majorBytes  = 2
+	majorText   = 3
+	majorArray  = 4
+	majorMap    = 5
+	majorTag    = 6
+	majorSimple = 7
+)
+
+const simpleNull = 22
+
+func appendHead(b []byte, major byte, n uint64) []byte {
+	m := major << 5
+	switch {
+	case n < 24:
+		return append(b, m|byte(n))
+	case n <= math.MaxUint8:
+		return append(b, m|24, byte(n))
+	case n <= math.MaxUint16:
+		return append(b, m|25, byte(n>>8), byte(n))
+	case n <= math.MaxUint32:
+		return append(b, m|26, byte(n>>24), byte(n>>16), byte(n>>8), byte(n))
+	default:
+		return append(b, m|27, byte(n>>56), byte(n>>48), byte(n>>40), byte(n>>32),
+			byte(n>>24), byte(n>>16), byte(n>>8), byte(n))
+	}
+}
+
+// AppendInt appends the encoding of an integer to b.
+func AppendInt(b []byte, n int64) []byte {
+	if n < 0 {
+		return appendHead(b, majorNegInt, uint64(-1-n))
+	}
+	return appendHead(b, majorUint, uint64(n))
+}
+
+// AppendBytes appends the encoding of a byte string to b.
+func AppendBytes(b, s []byte) []byte {
+	return append(appendHead(b, majorBytes, uint64(len(s))), s...)
+}
+
+// AppendText appends the encoding of a text string to b.
+func AppendText(b []byte, s string) []byte {
+	return append(appendHead(b, majorText, uint64(len(s))), s...)
+}
+
+// AppendArray appends the header of an array of n elements to b. The elements
+// must be appended next.
+func AppendArray(b []byte, n int) []byte {
+	return appendHead(b, majorArray, uint64(n))
+}
+
+// AppendMap appends the header of a map of n key-value pairs to b. The keys and
+// values must be appended next, alternating.
+func AppendMap(b []byte, n int) []byte {
+	return appendHead(b, majorMap, uint64(n))
+}
+
+// AppendTag appends a tag to b. The tagged item must be appended next.
+func AppendTag(b []byte, tag uint64) []byte {
+	return appendHead(b, majorTag, tag)
+}
+
+// AppendNull appends null to b.
+func AppendNull(b []byte) []byte {
+	return append(b, majorSimple<<5|simpleNull)
+}
+
+var (
+	errTruncated   = errors.New("cbor: unexpected end of input")
+	errType        = errors.New("cbor: unexpected type")
+	errNonMinimal  = errors.New("cbor: non-preferred serialization")
+	errUnsupported = errors.New("cbor: unsupported item")
+	errOverflow    = errors.New("cbor: value out of range")
+)
+
+// A Reader decodes CBOR items from a byte slice, in order.
+type Reader struct {
+	b []byte
+}
+
+// NewReader returns a Reader that decodes b.
+func NewReader(b []byte) *Reader {
+	return &Reader{b: b}
+}
+
+// Empty reports whether all input has been consumed.
+func (r *Reader) Empty() bool {
+	return len(r.b) == 0
+}
+
+// peekMajor returns the major type of the next item without consuming it.
+func (r *Reader) peekMajor() (byte, error) {
+	if len(r.b) == 0 {
+		return 0, errTruncated
+	}
+	return r.b[0] >> 5, nil
+}
+
+// readHead consumes the head of the next item, which must be of the given
+// major type, and returns its argument.
+func (r *Reader) readHead(major byte) (uint64, error) {
+	if len(r.b) == 0 {
+		return 0, errTruncated
+	}
+	if r.b[0]>>5 != major {
+		return 0, errType
+	}
+	info := r.b[0] & 0x1f
+	var size int
+	switch {
+	case info < 24:
+		r.b = r.b[1:]
+		return uint64(info), nil
+	case info == 24:
+		size = 1
+	case info == 25:
+		size = 2
+	case info == 26:
+		size = 4
+	case info == 27:
+		size = 8
+	default:
+		return 0, errUnsupported
+	}
+	if len(r.b) < 1+size {
+		return 0, errTruncated
+	}
+	var n uint64
+	for _, c := range r.b[1 : 1+size] {
+		n = n<<8 | uint64(c)
+	}
+	if (size == 1 && n < 24) || (size > 1 && n>>(size*8/2) == 0) {
+		return 0, errNonMinimal
+	}
+	r.b = r.b[1+size:]
+	return n, nil
+}
+
+// IsInt reports whether the next item is an integer.
+func (r *Reader) IsInt() bool {
+	m, err := r.peekMajor()
+	return err == nil && (m == majorUint || m == majorNegInt)
+}
+
+// IsBytes reports whether the next item is a byte string.
+func (r *Reader) IsBytes() bool {
+	m, err := r.peekMajor()
+	return err == nil && m == majorBytes
+}
+
+// IsNull reports whether the next item is null.
+func (r *Reader) IsNull() bool {
+	return len(r.b) > 0 && r.b[0] == majorSimple<<5|simpleNull
+}
+
+// ReadInt consumes an integer.
+func (r *Reader) ReadInt() (int64, error) {
+	m, err := r.peekMajor()
+	if err != nil {
+		return 0, err
+	}
+	switch m {
+	case majorUint:
+		n, err := r.readHead(majorUint)
+		if err != nil {
+			return 0, err
+		}
+		if n > math.MaxInt64 {
+			return 0, errOverflow
+		}
+		return int64(n), nil
+	case majorNegInt:
+		n, err := r.readHead(majorNegInt)
+		if err != nil {
+			return 0, err
+		}
+		if n > math.MaxInt64 {
+			return 0, errOverflow
+		}
+		return -1 - int64(n), nil
+	default:
+		return 0, errType
+	}
+}
+
+func (r *Reader) readString(major byte) ([]byte, error) {
+	n, err := r.readHead(major)
+	if err != nil {
+		return nil, err
+	}
+	if n > uint64(len(r.b)) {
+		return nil, errTruncated
+	}
+	s := r.b[:n:n]
+	r.b = r.b[n:]
+	return s, nil
+}
+
+// ReadBytes consumes a byte string. The returned slice aliases the input.
+func (r *Reader) ReadBytes() ([]byte, error) {
+	return r.readString(majorBytes)
+}
+
+// ReadText consumes a text string.
+func (r *Reader) ReadText() (string, error) {
+	s, err := r.readString(majorText)
+	return string(s), err
+}
+
+func (r *Reader) readLength(major byte) (int, error) {
+	n, err := r.readHead(major)
+	if err != nil {
+		return 0, err
+	}
+	// Each element takes at least one byte, which bounds n without overflow.
+	if n > uint64(len(r.b)) {
+		return 0, errTruncated
+	}
+	return int(n), nil
+}
+
+// ReadArray consumes the header of an array, and returns its number of
+// elements, which must be consumed next.
+func (r *Reader) ReadArray() (int, error) {
+	return r.readLength(majorArray)
+}
+
+// ReadMap consumes the header of a map, and returns its number of key-value
+// pairs, which must be consumed next.
+func (r *Reader) ReadMap() (int, error) {
+	n, err := r.readLength(majorMap)
+	if err != nil {
+		return 0, err
+	}
+	if uint64(n)*2 > uint64(len(r.b)) {
+		return 0, errTruncated
+	}
+	return n, nil
+}
+
+// ReadTag consumes a tag, and returns its number. The tagged item must be
+// consumed next.
+func (r *Reader) ReadTag() (uint64, error) {
+	return r.readHead(majorTag)
+}
+
+// ReadNull consumes null.
+func (r *Reader) ReadNull() error {
+	if !r.IsNull() {
+		return errType
+	}
+	r.b = r.b[1:]
+	return nil
+}
+
+// Skip consumes the next item, whatever its type, including any nested items.
+func (r *Reader) Skip() error {
+	return r.skip(0)
+}
+
+// maxDepth bounds the nesting of skipped items, to avoid unbounded recursion.
+const maxDepth = 16
+
+func (r *Reader) skip(depth int) error {
+	if depth > maxDepth {
+		return errUnsupported
+	}
+	m, err := r.peekMajor()
+	if err != nil {
+		return err
+	}
+	switch m {
+	case majorUint, majorNegInt:
+		_, err = r.readHead(m)
+	case majorBytes, majorText:
+		_, err = r.readString(m)
+	case majorArray, majorMap:
+		var n int
+		if n, err = r.readLength(m); err != nil {
+			return err
+		}
+		if m == majorMap {
+			n *= 2
+		}
+		for range n {
+			if err := r.skip(depth + 1); err != nil {
+				return err
+			}
+		}
+	case majorTag:
+		if _, err = r.readHead(m); err != nil {
+			return err
+		}
+		err = r.skip(depth + 1)
+	default:
+		err = r.ReadNull()
+	}
+	return err
+}
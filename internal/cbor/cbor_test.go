@@ -0,0 +1,277 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cbor
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"math"
+	"strings"
+	"testing"
+)
+
+func mustDecodeHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// TestInt checks the examples of RFC 8949, Appendix A, and the boundaries of
+// each argument size.
+func TestInt(t *testing.T) {
+	for _, tt := range []struct {
+		n   int64
+		hex string
+	}{
+		{0, "00"},
+		{1, "01"},
+		{10, "0a"},
+		{23, "17"},
+		{24, "1818"},
+		{25, "1819"},
+		{100, "1864"},
+		{255, "18ff"},
+		{256, "190100"},
+		{1000, "1903e8"},
+		{65535, "19ffff"},
+		{65536, "1a00010000"},
+		{1000000, "1a000f4240"},
+		{math.MaxUint32, "1affffffff"},
+		{math.MaxUint32 + 1, "1b0000000100000000"},
+		{1000000000000, "1b000000e8d4a51000"},
+		{math.MaxInt64, "1b7fffffffffffffff"},
+		{-1, "20"},
+		{-10, "29"},
+		{-24, "37"},
+		{-25, "3818"},
+		{-100, "3863"},
+		{-1000, "3903e7"},
+		{math.MinInt64, "3b7fffffffffffffff"},
+	} {
+		if got := hex.EncodeToString(AppendInt(nil, tt.n)); got != tt.hex {
+			t.Errorf("AppendInt(%d): got %s, expected %s", tt.n, got, tt.hex)
+		}
+		r := NewReader(mustDecodeHex(t, tt.hex))
+		if !r.IsInt() {
+			t.Errorf("%s: IsInt returned false", tt.hex)
+		}
+		if n, err := r.ReadInt(); err != nil || n != tt.n {
+			t.Errorf("ReadInt(%s): got %d, %v, expected %d", tt.hex, n, err, tt.n)
+		}
+		if !r.Empty() {
+			t.Errorf("ReadInt(%s): input not consumed", tt.hex)
+		}
+	}
+}
+
+func TestNonPreferred(t *testing.T) {
+	for _, h := range []string{
+		"1800",               // 0 in one extra byte
+		"1817",               // 23 in one extra byte
+		"190000",             // 0 in two extra bytes
+		"1900ff",             // 255 in two extra bytes
+		"1a0000ffff",         // 65535 in four extra bytes
+		"1b00000000ffffffff", // 2³² - 1 in eight extra bytes
+		"3817",               // -24 in one extra byte
+		"3900ff",             // -256 in two extra bytes
+		"5801ff",             // byte string of length 1 in one extra byte
+		"780161",             // text string of length 1 in one extra byte
+		"980100",             // array of length 1 in one extra byte
+		"b9000100",           // map of length 0 in two extra bytes
+	} {
+		r := NewReader(mustDecodeHex(t, h))
+		if err := r.Skip(); !errors.Is(err, errNonMinimal) {
+			t.Errorf("%s: got %v, expected %v", h, err, errNonMinimal)
+		}
+	}
+}
+
+func TestOverflow(t *testing.T) {
+	for _, h := range []string{
+		"1b8000000000000000", // 2⁶³
+		"1bffffffffffffffff", // 2⁶⁴ - 1
+		"3b8000000000000000", // -2⁶³ - 1
+	} {
+		if _, err := NewReader(mustDecodeHex(t, h)).ReadInt(); !errors.Is(err, errOverflow) {
+			t.Errorf("%s: got %v, expected %v", h, err, errOverflow)
+		}
+	}
+}
+
+func TestIndefinite(t *testing.T) {
+	for _, h := range []string{
+		"5f4101ff", // indefinite byte string
+		"7f6161ff", // indefinite text string
+		"9f01ff",   // indefinite array
+		"bf0101ff", // indefinite map
+		"1c",       // reserved additional information
+		"1e",       // reserved additional information
+		"ff",       // break
+	} {
+		r := NewReader(mustDecodeHex(t, h))
+		if err := r.Skip(); err == nil {
+			t.Errorf("%s: Skip: expected error", h)
+		}
+	}
+	if _, err := NewReader(mustDecodeHex(t, "5f4101ff")).ReadBytes(); !errors.Is(err, errUnsupported) {
+		t.Errorf("ReadBytes: got %v, expected %v", err, errUnsupported)
+	}
+	if _, err := NewReader(mustDecodeHex(t, "9f01ff")).ReadArray(); !errors.Is(err, errUnsupported) {
+		t.Errorf("ReadArray: got %v, expected %v", err, errUnsupported)
+	}
+	if _, err := NewReader(mustDecodeHex(t, "bf0101ff")).ReadMap(); !errors.Is(err, errUnsupported) {
+		t.Errorf("ReadMap: got %v, expected %v", err, errUnsupported)
+	}
+}
+
+func TestUnsupportedSimple(t *testing.T) {
+	for _, h := range []string{
+		"f4",         // false
+		"f5",         // true
+		"f7",         // undefined
+		"f93c00",     // half-precision 1.0
+		"fa3f800000", // single-precision 1.0
+	} {
+		if err := NewReader(mustDecodeHex(t, h)).Skip(); err == nil {
+			t.Errorf("%s: expected error", h)
+		}
+	}
+}
+
+func TestStrings(t *testing.T) {
+	long := bytes.Repeat([]byte{0xaa}, 300)
+	for _, s := range [][]byte{nil, {1, 2, 3}, bytes.Repeat([]byte{0xaa}, 24), long} {
+		b := AppendBytes(nil, s)
+		got, err := NewReader(b).ReadBytes()
+		if err != nil || !bytes.Equal(got, s) {
+			t.Errorf("ReadBytes(%x): got %x, %v", b, got, err)
+		}
+		txt, err := NewReader(AppendText(nil, string(s))).ReadText()
+		if err != nil || txt != string(s) {
+			t.Errorf("ReadText: got %q, %v", txt, err)
+		}
+	}
+	if _, err := NewReader(AppendText(nil, "a")).ReadBytes(); !errors.Is(err, errType) {
+		t.Errorf("ReadBytes of a text string: got %v, expected %v", err, errType)
+	}
+}
+
+func TestStructures(t *testing.T) {
+	// {1: [h'01', "a", null], -1: 96([])}
+	var b []byte
+	b = AppendMap(b, 2)
+	b = AppendInt(b, 1)
+	b = AppendArray(b, 3)
+	b = AppendBytes(b, []byte{1})
+	b = AppendText(b, "a")
+	b = AppendNull(b)
+	b = AppendInt(b, -1)
+	b = AppendTag(b, 96)
+	b = AppendArray(b, 0)
+	if got, want := hex.EncodeToString(b), "a2018341016161f620d86080"; got != want {
+		t.Fatalf("got %s, expected %s", got, want)
+	}
+
+	r := NewReader(b)
+	if n, err := r.ReadMap(); err != nil || n != 2 {
+		t.Fatalf("ReadMap: got %d, %v", n, err)
+	}
+	if n, err := r.ReadInt(); err != nil || n != 1 {
+		t.Fatalf("ReadInt: got %d, %v", n, err)
+	}
+	if n, err := r.ReadArray(); err != nil || n != 3 {
+		t.Fatalf("ReadArray: got %d, %v", n, err)
+	}
+	if !r.IsBytes() {
+		t.Fatal("IsBytes returned false")
+	}
+	if err := r.Skip(); err != nil {
+		t.Fatal(err)
+	}
+	if s, err := r.ReadText(); err != nil || s != "a" {
+		t.Fatalf("ReadText: got %q, %v", s, err)
+	}
+	if !r.IsNull() {
+		t.Fatal("IsNull returned false")
+	}
+	if err := r.ReadNull(); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := r.ReadInt(); err != nil || n != -1 {
+		t.Fatalf("ReadInt: got %d, %v", n, err)
+	}
+	if tag, err := r.ReadTag(); err != nil || tag != 96 {
+		t.Fatalf("ReadTag: got %d, %v", tag, err)
+	}
+	if n, err := r.ReadArray(); err != nil || n != 0 {
+		t.Fatalf("ReadArray: got %d, %v", n, err)
+	}
+	if !r.Empty() {
+		t.Fatal("input not consumed")
+	}
+
+	r = NewReader(b)
+	if err := r.Skip(); err != nil || !r.Empty() {
+		t.Errorf("Skip: got %v, empty %v", err, r.Empty())
+	}
+}
+
+func TestTruncated(t *testing.T) {
+	var b []byte
+	b = AppendMap(b, 2)
+	b = AppendInt(b, 1000)
+	b = AppendBytes(b, bytes.Repeat([]byte{0xaa}, 30))
+	b = AppendText(b, "key")
+	b = AppendTag(b, 96)
+	b = AppendArray(b, 2)
+	b = AppendInt(b, -70000)
+	b = AppendNull(b)
+	if err := NewReader(b).Skip(); err != nil {
+		t.Fatal(err)
+	}
+	for i := range len(b) {
+		if err := NewReader(b[:i]).Skip(); !errors.Is(err, errTruncated) {
+			t.Errorf("truncated to %d bytes: got %v, expected %v", i, err, errTruncated)
+		}
+	}
+
+	// Lengths that exceed the remaining input are rejected before any
+	// elements are read.
+	for _, h := range []string{
+		"5a7fffffff",         // byte string of length 2³¹ - 1
+		"7b7fffffffffffffff", // text string of length 2⁶³ - 1
+		"9bffffffffffffffff", // array of length 2⁶⁴ - 1
+		"a201",               // map of two pairs with one byte left
+	} {
+		if err := NewReader(mustDecodeHex(t, h)).Skip(); !errors.Is(err, errTruncated) {
+			t.Errorf("%s: got %v, expected %v", h, err, errTruncated)
+		}
+	}
+}
+
+func TestDepth(t *testing.T) {
+	nested := func(depth int) []byte {
+		return append(bytes.Repeat([]byte{0x81}, depth), 0x00)
+	}
+	if err := NewReader(nested(maxDepth)).Skip(); err != nil {
+		t.Errorf("depth %d: %v", maxDepth, err)
+	}
+	if err := NewReader(nested(maxDepth + 1)).Skip(); !errors.Is(err, errUnsupported) {
+		t.Errorf("depth %d: got %v, expected %v", maxDepth+1, err, errUnsupported)
+	}
+	// Tags count towards the depth too.
+	tagged := append(bytes.Repeat([]byte{0xc1}, maxDepth+1), 0x00)
+	if err := NewReader(tagged).Skip(); !errors.Is(err, errUnsupported) {
+		t.Errorf("nested tags: got %v, expected %v", err, errUnsupported)
+	}
+	deep := []byte(strings.Repeat("\x81", 100000))
+	if err := NewReader(deep).Skip(); err == nil {
+		t.Errorf("very deep nesting: expected error")
+	}
+}
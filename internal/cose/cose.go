@@ -0,0 +1,393 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package cose implements the COSE (RFC 9052) structures shared by the
+// mlkem768 and xwing packages: COSE_Key with the AKP key type, and
+// COSE_Encrypt messages with KEM recipients.
+//
+// The recipient format is private to this module. It borrows the "ek" header
+// parameter of COSE-HPKE, but wraps the content key with its own HKDF-SHA256
+// and AES-256-GCM construction rather than HPKE, so it doesn't interoperate
+// with other implementations.
+package cose
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+
+	"filippo.io/mlkem768/internal/cbor"
+)
+
+// Registered COSE labels and values.
+const (
+	// KeyTypeAKP is the Algorithm Key Pair key type, whose algorithm
+	// determines the format of the "pub" and "priv" parameters.
+	KeyTypeAKP = 7
+
+	labelKty  = 1
+	labelAlg  = 3
+	labelPub  = -1
+	labelPriv = -2
+
+	headerAlg = 1
+	headerKid = 4
+	headerIV  = 5
+	// headerEK is the "ek" header parameter of COSE-HPKE, which carries the
+	// KEM ciphertext in a recipient structure.
+	headerEK = -4
+
+	algA256GCM = 3
+
+	tagEncrypt = 96
+)
+
+// MarshalKey encodes a COSE_Key of type AKP with the given algorithm. priv is
+// omitted if nil.
+func MarshalKey(alg int64, pub, priv []byte) []byte {
+	// Labels are in the deterministic order of RFC 8949, Section 4.2.1: 1, 3,
+	// -1, -2.
+	n := 3
+	if priv != nil {
+		n++
+	}
+	b := cbor.AppendMap(nil, n)
+	b = cbor.AppendInt(b, labelKty)
+	b = cbor.AppendInt(b, KeyTypeAKP)
+	b = cbor.AppendInt(b, labelAlg)
+	b = cbor.AppendInt(b, alg)
+	b = cbor.AppendInt(b, labelPub)
+	b = cbor.AppendBytes(b, pub)
+	if priv != nil {
+		b = cbor.AppendInt(b, labelPriv)
+		b = cbor.AppendBytes(b, priv)
+	}
+	return b
+}
+
+// ParseKey decodes a COSE_Key of type AKP, which must have the given
+// algorithm. priv is nil if absent. Unknown labels and kid are ignored.
+func ParseKey(data []byte, alg int64) (pub, priv []byte, err error) {
+	r := cbor.NewReader(data)
+	n, err := r.ReadMap()
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid COSE_Key: %w", err)
+	}
+	var kty, keyAlg int64
+	seen, seenText := make(map[int64]bool), make(map[string]bool)
+	for range n {
+		if !r.IsInt() {
+			if err := skipTextPair(r, seenText); err != nil {
+				return nil, nil, fmt.Errorf("invalid COSE_Key: %w", err)
+			}
+			continue
+		}
+		label, err := r.ReadInt()
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid COSE_Key: %w", err)
+		}
+		if seen[label] {
+			return nil, nil, fmt.Errorf("invalid COSE_Key: duplicate label %d", label)
+		}
+		seen[label] = true
+		switch label {
+		case labelKty:
+			kty, err = r.ReadInt()
+		case labelAlg:
+			keyAlg, err = r.ReadInt()
+		case labelPub:
+			pub, err = r.ReadBytes()
+		case labelPriv:
+			priv, err = r.ReadBytes()
+		default:
+			err = r.Skip()
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid COSE_Key: label %d: %w", label, err)
+		}
+	}
+	if !r.Empty() {
+		return nil, nil, errors.New("invalid COSE_Key: trailing data")
+	}
+	if !seen[labelKty] || kty != KeyTypeAKP {
+		return nil, nil, fmt.Errorf("unsupported COSE_Key type %d", kty)
+	}
+	if !seen[labelAlg] || keyAlg != alg {
+		return nil, nil, fmt.Errorf("unsupported COSE_Key algorithm %d", keyAlg)
+	}
+	if !seen[labelPub] {
+		return nil, nil, errors.New("invalid COSE_Key: missing pub")
+	}
+	return pub, priv, nil
+}
+
+// skipTextPair consumes a map entry with a text label, which no parameter used
+// here has. Labels must be integers or text strings, and must not repeat.
+func skipTextPair(r *cbor.Reader, seen map[string]bool) error {
+	label, err := r.ReadText()
+	if err != nil {
+		return fmt.Errorf("invalid label: %w", err)
+	}
+	if seen[label] {
+		return fmt.Errorf("duplicate label %q", label)
+	}
+	seen[label] = true
+	return r.Skip()
+}
+
+// Seal encrypts plaintext with AES-256-GCM under a random content key, and
+// returns a tagged COSE_Encrypt message with a single recipient, for which the
+// content key is wrapped with the shared key produced by encapsulate.
+//
+// The recipient has the KEM algorithm alg in its protected header, the KEM
+// ciphertext in the "ek" unprotected header parameter, and optionally kid. The
+// wrapping key is derived from the shared key with HKDF-SHA256, using the
+// recipient Enc_structure as info, and the content key is encrypted with
+// AES-256-GCM under the wrapping key, a zero nonce, and the same
+// Enc_structure as additional data. The wrapping key is used only once, so a
+// fixed nonce is safe.
+func Seal(alg int64, kid []byte, encapsulate func() (ciphertext, sharedKey []byte, err error), plaintext, externalAAD []byte) ([]byte, error) {
+	contentKey := make([]byte, 32)
+	rand.Read(contentKey)
+	iv := make([]byte, 12)
+	rand.Read(iv)
+
+	protected := cbor.AppendMap(nil, 1)
+	protected = cbor.AppendInt(protected, headerAlg)
+	protected = cbor.AppendInt(protected, algA256GCM)
+	aead, err := newGCM(contentKey)
+	if err != nil {
+		return nil, err
+	}
+	ciphertext := aead.Seal(nil, iv, plaintext, encStructure("Encrypt", protected, externalAAD))
+
+	kemCiphertext, sharedKey, err := encapsulate()
+	if err != nil {
+		return nil, err
+	}
+	recipientProtected := cbor.AppendMap(nil, 1)
+	recipientProtected = cbor.AppendInt(recipientProtected, headerAlg)
+	recipientProtected = cbor.AppendInt(recipientProtected, alg)
+	wrapped, err := wrapKey(sharedKey, recipientProtected, contentKey, false)
+	if err != nil {
+		return nil, err
+	}
+
+	b := cbor.AppendTag(nil, tagEncrypt)
+	b = cbor.AppendArray(b, 4)
+	b = cbor.AppendBytes(b, protected)
+	b = cbor.AppendMap(b, 1)
+	b = cbor.AppendInt(b, headerIV)
+	b = cbor.AppendBytes(b, iv)
+	b = cbor.AppendBytes(b, ciphertext)
+
+	b = cbor.AppendArray(b, 1)
+	b = cbor.AppendArray(b, 3)
+	b = cbor.AppendBytes(b, recipientProtected)
+	if kid != nil {
+		b = cbor.AppendMap(b, 2)
+		b = cbor.AppendInt(b, headerKid)
+		b = cbor.AppendBytes(b, kid)
+	} else {
+		b = cbor.AppendMap(b, 1)
+	}
+	b = cbor.AppendInt(b, headerEK)
+	b = cbor.AppendBytes(b, kemCiphertext)
+	b = cbor.AppendBytes(b, wrapped)
+	return b, nil
+}
+
+// Open decrypts a COSE_Encrypt message produced by Seal. It tries each
+// recipient with the KEM algorithm alg in turn, unwrapping the content key with
+// the shared key returned by decapsulate.
+func Open(alg int64, decapsulate func(ciphertext []byte) (sharedKey []byte, err error), message, externalAAD []byte) ([]byte, error) {
+	r := cbor.NewReader(message)
+	if tag, err := r.ReadTag(); err != nil || tag != tagEncrypt {
+		return nil, errors.New("invalid COSE_Encrypt: missing tag")
+	}
+	if n, err := r.ReadArray(); err != nil || n != 4 {
+		return nil, errors.New("invalid COSE_Encrypt: not a four-element array")
+	}
+	protected, err := r.ReadBytes()
+	if err != nil {
+		return nil, fmt.Errorf("invalid COSE_Encrypt: protected header: %w", err)
+	}
+	if a, err := parseProtectedAlg(protected); err != nil {
+		return nil, fmt.Errorf("invalid COSE_Encrypt: %w", err)
+	} else if a != algA256GCM {
+		return nil, fmt.Errorf("unsupported COSE_Encrypt algorithm %d", a)
+	}
+	headers, err := parseUnprotected(r)
+	if err != nil {
+		return nil, fmt.Errorf("invalid COSE_Encrypt: %w", err)
+	}
+	iv := headers[headerIV]
+	if len(iv) != 12 {
+		return nil, errors.New("invalid COSE_Encrypt: missing or invalid IV")
+	}
+	ciphertext, err := r.ReadBytes()
+	if err != nil {
+		return nil, fmt.Errorf("invalid COSE_Encrypt: ciphertext: %w", err)
+	}
+
+	n, err := r.ReadArray()
+	if err != nil {
+		return nil, fmt.Errorf("invalid COSE_Encrypt: recipients: %w", err)
+	}
+	type recipient struct {
+		protected, ek, wrapped []byte
+	}
+	var recipients []recipient
+	for range n {
+		if n, err := r.ReadArray(); err != nil || n != 3 {
+			return nil, errors.New("invalid COSE_recipient: not a three-element array")
+		}
+		protected, err := r.ReadBytes()
+		if err != nil {
+			return nil, fmt.Errorf("invalid COSE_recipient: protected header: %w", err)
+		}
+		a, err := parseProtectedAlg(protected)
+		if err != nil {
+			return nil, fmt.Errorf("invalid COSE_recipient: %w", err)
+		}
+		headers, err := parseUnprotected(r)
+		if err != nil {
+			return nil, fmt.Errorf("invalid COSE_recipient: %w", err)
+		}
+		if r.IsNull() {
+			// A recipient without a ciphertext, which is not ours.
+			r.ReadNull()
+			continue
+		}
+		wrapped, err := r.ReadBytes()
+		if err != nil {
+			return nil, fmt.Errorf("invalid COSE_recipient: ciphertext: %w", err)
+		}
+		if a == alg {
+			recipients = append(recipients, recipient{protected, headers[headerEK], wrapped})
+		}
+	}
+	if !r.Empty() {
+		return nil, errors.New("invalid COSE_Encrypt: trailing data")
+	}
+
+	for _, rcpt := range recipients {
+		sharedKey, err := decapsulate(rcpt.ek)
+		if err != nil {
+			continue
+		}
+		contentKey, err := wrapKey(sharedKey, rcpt.protected, rcpt.wrapped, true)
+		if err != nil {
+			continue
+		}
+		aead, err := newGCM(contentKey)
+		if err != nil {
+			return nil, err
+		}
+		plaintext, err := aead.Open(nil, iv, ciphertext, encStructure("Encrypt", protected, externalAAD))
+		if err != nil {
+			return nil, errors.New("COSE_Encrypt decryption failed")
+		}
+		return plaintext, nil
+	}
+	return nil, errors.New("no COSE_recipient could be decrypted")
+}
+
+// wrapKey encrypts, or decrypts if open is true, the content key with the
+// wrapping key derived from sharedKey.
+func wrapKey(sharedKey, recipientProtected, in []byte, open bool) ([]byte, error) {
+	context := encStructure("Enc_Recipient", recipientProtected, nil)
+	kek, err := hkdf.Key(sha256.New, sharedKey, nil, string(context), 32)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newGCM(kek)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if open {
+		return aead.Open(nil, nonce, in, context)
+	}
+	return aead.Seal(nil, nonce, in, context), nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// encStructure returns the Enc_structure of RFC 9052, Section 5.3.
+func encStructure(context string, protected, externalAAD []byte) []byte {
+	b := cbor.AppendArray(nil, 3)
+	b = cbor.AppendText(b, context)
+	b = cbor.AppendBytes(b, protected)
+	b = cbor.AppendBytes(b, externalAAD)
+	return b
+}
+
+// parseProtectedAlg parses a serialized protected header map, which must
+// contain only the alg parameter.
+func parseProtectedAlg(protected []byte) (int64, error) {
+	r := cbor.NewReader(protected)
+	if n, err := r.ReadMap(); err != nil || n != 1 {
+		return 0, errors.New("protected header must contain only alg")
+	}
+	if label, err := r.ReadInt(); err != nil || label != headerAlg {
+		return 0, errors.New("protected header must contain only alg")
+	}
+	alg, err := r.ReadInt()
+	if err != nil {
+		return 0, fmt.Errorf("protected header alg: %w", err)
+	}
+	if !r.Empty() {
+		return 0, errors.New("trailing data after protected header")
+	}
+	return alg, nil
+}
+
+// parseUnprotected parses an unprotected header map, returning the byte string
+// parameters with integer labels. Other parameters are ignored, but no label
+// may appear twice, whatever its type and the type of its value.
+func parseUnprotected(r *cbor.Reader) (map[int64][]byte, error) {
+	n, err := r.ReadMap()
+	if err != nil {
+		return nil, fmt.Errorf("unprotected header: %w", err)
+	}
+	headers := make(map[int64][]byte)
+	seen, seenText := make(map[int64]bool), make(map[string]bool)
+	for range n {
+		if !r.IsInt() {
+			if err := skipTextPair(r, seenText); err != nil {
+				return nil, fmt.Errorf("unprotected header: %w", err)
+			}
+			continue
+		}
+		label, err := r.ReadInt()
+		if err != nil {
+			return nil, fmt.Errorf("unprotected header: %w", err)
+		}
+		if seen[label] {
+			return nil, fmt.Errorf("unprotected header: duplicate label %d", label)
+		}
+		seen[label] = true
+		if !r.IsBytes() {
+			if err := r.Skip(); err != nil {
+				return nil, fmt.Errorf("unprotected header: %w", err)
+			}
+			continue
+		}
+		if headers[label], err = r.ReadBytes(); err != nil {
+			return nil, fmt.Errorf("unprotected header: %w", err)
+		}
+	}
+	return headers, nil
+}
@@ -0,0 +1,125 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cose
+
+import (
+	"bytes"
+	"testing"
+
+	"filippo.io/mlkem768/internal/cbor"
+)
+
+// An item appends a CBOR item, to assemble maps in tests.
+type item func([]byte) []byte
+
+func integer(n int64) item { return func(b []byte) []byte { return cbor.AppendInt(b, n) } }
+func text(s string) item   { return func(b []byte) []byte { return cbor.AppendText(b, s) } }
+func bstr(s string) item   { return func(b []byte) []byte { return cbor.AppendBytes(b, []byte(s)) } }
+
+func TestParseKey(t *testing.T) {
+	pub, priv := []byte("public"), []byte("private")
+	p, s, err := ParseKey(MarshalKey(-1, pub, priv), -1)
+	if err != nil || !bytes.Equal(p, pub) || !bytes.Equal(s, priv) {
+		t.Errorf("private key: got %q, %q, %v", p, s, err)
+	}
+	p, s, err = ParseKey(MarshalKey(-1, pub, nil), -1)
+	if err != nil || !bytes.Equal(p, pub) || s != nil {
+		t.Errorf("public key: got %q, %q, %v", p, s, err)
+	}
+
+	// key appends the given label-value pairs to the kty, alg, and pub ones.
+	key := func(extra ...item) []byte {
+		b := cbor.AppendMap(nil, 3+len(extra)/2)
+		b = cbor.AppendInt(b, labelKty)
+		b = cbor.AppendInt(b, KeyTypeAKP)
+		b = cbor.AppendInt(b, labelAlg)
+		b = cbor.AppendInt(b, -1)
+		b = cbor.AppendInt(b, labelPub)
+		b = cbor.AppendBytes(b, pub)
+		for _, f := range extra {
+			b = f(b)
+		}
+		return b
+	}
+	// 2 is the kid label of COSE_Key.
+	if _, _, err := ParseKey(key(integer(2), bstr("kid"), text("x"), integer(1)), -1); err != nil {
+		t.Errorf("unknown labels: %v", err)
+	}
+	for name, data := range map[string][]byte{
+		"duplicate kty":       key(integer(labelKty), integer(KeyTypeAKP)),
+		"duplicate pub":       key(integer(labelPub), bstr("other")),
+		"duplicate unknown":   key(integer(-100), integer(1), integer(-100), integer(1)),
+		"duplicate text":      key(text("x"), integer(1), text("x"), bstr("y")),
+		"byte string label":   key(bstr("x"), integer(1)),
+		"wrong alg":           MarshalKey(-2, pub, nil),
+		"trailing data":       append(MarshalKey(-1, pub, nil), 0),
+		"truncated":           MarshalKey(-1, pub, nil)[:10],
+		"not a map":           cbor.AppendArray(nil, 0),
+		"indefinite length":   {0xbf, 0xff},
+		"non-preferred label": append(cbor.AppendMap(nil, 3), 0x18, 0x01),
+	} {
+		if _, _, err := ParseKey(data, -1); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestParseUnprotected(t *testing.T) {
+	headers := func(pairs ...item) []byte {
+		b := cbor.AppendMap(nil, len(pairs)/2)
+		for _, f := range pairs {
+			b = f(b)
+		}
+		return b
+	}
+
+	got, err := parseUnprotected(cbor.NewReader(headers(
+		integer(headerKid), bstr("kid"), integer(headerAlg), integer(1), text("x"), bstr("y"))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || string(got[headerKid]) != "kid" {
+		t.Errorf("got %q", got)
+	}
+
+	for name, data := range map[string][]byte{
+		"duplicate bstr":          headers(integer(headerIV), bstr("a"), integer(headerIV), bstr("b")),
+		"duplicate int value":     headers(integer(headerAlg), integer(1), integer(headerAlg), integer(1)),
+		"duplicate mixed values":  headers(integer(headerKid), integer(1), integer(headerKid), bstr("kid")),
+		"duplicate mixed reverse": headers(integer(headerKid), bstr("kid"), integer(headerKid), integer(1)),
+		"duplicate text label":    headers(text("x"), integer(1), text("x"), integer(1)),
+		"byte string label":       headers(bstr("x"), integer(1)),
+		"truncated":               headers(integer(headerIV), bstr("a"))[:3],
+	} {
+		if _, err := parseUnprotected(cbor.NewReader(data)); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestSealOpen(t *testing.T) {
+	sharedKey := bytes.Repeat([]byte{1}, 32)
+	encapsulate := func() ([]byte, []byte, error) { return []byte("kem ciphertext"), sharedKey, nil }
+	decapsulate := func(c []byte) ([]byte, error) {
+		if string(c) != "kem ciphertext" {
+			t.Errorf("decapsulate: got %q", c)
+		}
+		return sharedKey, nil
+	}
+	msg, err := Seal(-1, []byte("kid"), encapsulate, []byte("plaintext"), []byte("aad"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := Open(-1, decapsulate, msg, []byte("aad"))
+	if err != nil || string(got) != "plaintext" {
+		t.Errorf("Open: got %q, %v", got, err)
+	}
+	if _, err := Open(-2, decapsulate, msg, []byte("aad")); err == nil {
+		t.Errorf("Open: expected error for a different algorithm")
+	}
+	if _, err := Open(-1, decapsulate, append(msg, 0), []byte("aad")); err == nil {
+		t.Errorf("Open: expected error for trailing data")
+	}
+}
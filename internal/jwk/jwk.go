@@ -0,0 +1,154 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package jwk implements the JSON Web Key (RFC 7517) representation shared by
+// the mlkem768 and xwing packages: the Algorithm Key Pair (AKP) key type of
+// [draft-ietf-jose-pqc-kem], where "pub" is the public key and "priv" is the
+// private seed, both base64url encoded without padding.
+//
+// [draft-ietf-jose-pqc-kem]: https://datatracker.ietf.org/doc/draft-ietf-jose-pqc-kem/
+package jwk
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// KeyTypeAKP is the Algorithm Key Pair key type, whose algorithm determines
+// the format of the "pub" and "priv" members.
+const KeyTypeAKP = "AKP"
+
+type key struct {
+	Kty  string `json:"kty"`
+	Alg  string `json:"alg"`
+	Pub  string `json:"pub"`
+	Priv string `json:"priv,omitempty"`
+}
+
+var b64 = base64.RawURLEncoding.Strict()
+
+// MarshalKey encodes an AKP key for the algorithm alg. If priv is nil, the
+// "priv" member is omitted.
+func MarshalKey(alg string, pub, priv []byte) []byte {
+	k := key{Kty: KeyTypeAKP, Alg: alg, Pub: b64.EncodeToString(pub)}
+	if priv != nil {
+		k.Priv = b64.EncodeToString(priv)
+	}
+	out, err := json.Marshal(k)
+	if err != nil {
+		panic("jwk: failed to marshal key: " + err.Error())
+	}
+	return out
+}
+
+// ParseKey parses an AKP key for the algorithm alg, returning the decoded
+// "pub" and "priv" members. priv is nil if the "priv" member is absent.
+//
+// Unlike encoding/json, ParseKey matches member names exactly, and rejects
+// duplicate members and members whose names differ from the known ones only
+// by case, so that all parsers agree on the key. Other members are ignored.
+func ParseKey(data []byte, alg string) (pub, priv []byte, err error) {
+	members, err := parseObject(data)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid JWK: %w", err)
+	}
+	var k key
+	for _, m := range []struct {
+		name     string
+		v        *string
+		optional bool
+	}{
+		{"kty", &k.Kty, false},
+		{"alg", &k.Alg, false},
+		{"pub", &k.Pub, false},
+		{"priv", &k.Priv, true},
+	} {
+		raw, ok := members[m.name]
+		if !ok {
+			if m.optional {
+				continue
+			}
+			return nil, nil, fmt.Errorf("JWK has no %s member", m.name)
+		}
+		// json.Unmarshal accepts null for a string, leaving it unset.
+		if !bytes.HasPrefix(raw, []byte(`"`)) {
+			return nil, nil, fmt.Errorf("JWK %s member is not a string", m.name)
+		}
+		if err := json.Unmarshal(raw, m.v); err != nil {
+			return nil, nil, fmt.Errorf("invalid JWK %s member: %w", m.name, err)
+		}
+	}
+	if k.Kty != KeyTypeAKP {
+		return nil, nil, fmt.Errorf("unsupported JWK key type %q", k.Kty)
+	}
+	if k.Alg != alg {
+		return nil, nil, fmt.Errorf("unsupported JWK algorithm %q", k.Alg)
+	}
+	pub, err = b64.DecodeString(k.Pub)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid JWK pub member: %w", err)
+	}
+	if _, ok := members["priv"]; ok {
+		priv, err = b64.DecodeString(k.Priv)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid JWK priv member: %w", err)
+		}
+	}
+	return pub, priv, nil
+}
+
+// parseObject returns the members of the JSON object in data, rejecting
+// duplicate names and names that match a known member only up to case.
+func parseObject(data []byte) (map[string]json.RawMessage, error) {
+	d := json.NewDecoder(bytes.NewReader(data))
+	if t, err := d.Token(); err != nil {
+		return nil, err
+	} else if t != json.Delim('{') {
+		return nil, errors.New("not a JSON object")
+	}
+	members := make(map[string]json.RawMessage)
+	for d.More() {
+		t, err := d.Token()
+		if err != nil {
+			return nil, err
+		}
+		name := t.(string) // object keys are always strings
+		if _, ok := members[name]; ok {
+			return nil, fmt.Errorf("duplicate member %q", name)
+		}
+		for _, known := range []string{"kty", "alg", "pub", "priv"} {
+			if name != known && strings.EqualFold(name, known) {
+				return nil, fmt.Errorf("member %q doesn't match the case of %q", name, known)
+			}
+		}
+		var v json.RawMessage
+		if err := d.Decode(&v); err != nil {
+			return nil, err
+		}
+		members[name] = v
+	}
+	if _, err := d.Token(); err != nil {
+		return nil, err
+	}
+	if _, err := d.Token(); err != io.EOF {
+		return nil, errors.New("trailing data after JSON object")
+	}
+	return members, nil
+}
+
+// Thumbprint returns the JWK Thumbprint (RFC 7638) of an AKP public key, the
+// base64url encoding of the SHA-256 hash of its required members.
+func Thumbprint(alg string, pub []byte) string {
+	// The required members, in lexicographic order and without whitespace.
+	// The algorithm names and base64url don't need escaping.
+	h := sha256.Sum256([]byte(`{"alg":"` + alg + `","kty":"` + KeyTypeAKP +
+		`","pub":"` + b64.EncodeToString(pub) + `"}`))
+	return b64.EncodeToString(h[:])
+}
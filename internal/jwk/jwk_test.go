@@ -0,0 +1,76 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package jwk
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"testing"
+)
+
+func TestParseKey(t *testing.T) {
+	pub, priv := []byte("public key"), []byte("private seed")
+	for _, tt := range []struct {
+		name string
+		data []byte
+		priv []byte
+	}{
+		{"private", MarshalKey("ALG", pub, priv), priv},
+		{"public", MarshalKey("ALG", pub, nil), nil},
+		{"extra members", []byte(`{"kid":"1","kty":"AKP","alg":"ALG","use":"enc","pub":"cHVibGljIGtleQ"}`), nil},
+		{"whitespace", []byte(" {\n\"kty\" : \"AKP\", \"alg\": \"ALG\", \"pub\": \"cHVibGljIGtleQ\" }\n"), nil},
+	} {
+		p, s, err := ParseKey(tt.data, "ALG")
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if !bytes.Equal(p, pub) || !bytes.Equal(s, tt.priv) || (s == nil) != (tt.priv == nil) {
+			t.Errorf("%s: got %q, %q, expected %q, %q", tt.name, p, s, pub, tt.priv)
+		}
+	}
+
+	for name, data := range map[string]string{
+		"duplicate kty":   `{"kty":"AKP","kty":"AKP","alg":"ALG","pub":"cHVibGljIGtleQ"}`,
+		"duplicate pub":   `{"kty":"AKP","alg":"ALG","pub":"cHVibGljIGtleQ","pub":"b3RoZXI"}`,
+		"duplicate priv":  `{"kty":"AKP","alg":"ALG","pub":"cHVibGljIGtleQ","priv":"","priv":"cHJpdg"}`,
+		"duplicate extra": `{"kid":"1","kty":"AKP","alg":"ALG","pub":"cHVibGljIGtleQ","kid":"2"}`,
+		"case kty":        `{"KTY":"AKP","alg":"ALG","pub":"cHVibGljIGtleQ"}`,
+		"case alg":        `{"kty":"AKP","Alg":"ALG","pub":"cHVibGljIGtleQ"}`,
+		"case pub":        `{"kty":"AKP","alg":"ALG","pUb":"cHVibGljIGtleQ"}`,
+		"case priv":       `{"kty":"AKP","alg":"ALG","pub":"cHVibGljIGtleQ","PRIV":"cHJpdg"}`,
+		"case and exact":  `{"kty":"AKP","alg":"ALG","pub":"cHVibGljIGtleQ","Pub":"b3RoZXI"}`,
+		"kelvin sign kty": "{\"\u212aty\":\"AKP\",\"alg\":\"ALG\",\"pub\":\"cHVibGljIGtleQ\"}",
+		"missing kty":     `{"alg":"ALG","pub":"cHVibGljIGtleQ"}`,
+		"missing pub":     `{"kty":"AKP","alg":"ALG"}`,
+		"null pub":        `{"kty":"AKP","alg":"ALG","pub":null}`,
+		"number alg":      `{"kty":"AKP","alg":1,"pub":"cHVibGljIGtleQ"}`,
+		"wrong kty":       `{"kty":"OKP","alg":"ALG","pub":"cHVibGljIGtleQ"}`,
+		"wrong alg":       `{"kty":"AKP","alg":"alg","pub":"cHVibGljIGtleQ"}`,
+		"padded pub":      `{"kty":"AKP","alg":"ALG","pub":"cHVibGljIGtleQ=="}`,
+		"array":           `[{"kty":"AKP","alg":"ALG","pub":"cHVibGljIGtleQ"}]`,
+		"trailing data":   `{"kty":"AKP","alg":"ALG","pub":"cHVibGljIGtleQ"}{}`,
+		"truncated":       `{"kty":"AKP","alg":"ALG","pub":"cHVibGljIGtleQ"`,
+		"empty":           ``,
+	} {
+		if _, _, err := ParseKey([]byte(data), "ALG"); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestThumbprint(t *testing.T) {
+	pub := []byte("public key")
+	// encoding/json sorts map keys and doesn't add whitespace, like RFC 7638.
+	required, err := json.Marshal(map[string]string{"kty": "AKP", "alg": "ALG", "pub": b64.EncodeToString(pub)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := sha256.Sum256(required)
+	if got, want := Thumbprint("ALG", pub), b64.EncodeToString(h[:]); got != want {
+		t.Errorf("got %s, expected %s", got, want)
+	}
+}
@@ -0,0 +1,39 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package keytext implements the text encoding and the redacted formatting of
+// keys shared by the mlkem768 and xwing packages.
+//
+// The text encoding of keys is the standard, padded base64 encoding of their
+// binary encoding, which makes them usable as JSON strings and in other text
+// configuration formats.
+package keytext
+
+import (
+	"encoding/base64"
+	"fmt"
+)
+
+var b64 = base64.StdEncoding.Strict()
+
+// Encode returns the text encoding of the binary encoding b.
+func Encode(b []byte) []byte {
+	return b64.AppendEncode(nil, b)
+}
+
+// Decode returns the binary encoding encoded in text, or an error matching
+// errInvalid if text is not valid base64.
+func Decode(text []byte, errInvalid error) ([]byte, error) {
+	b, err := b64.AppendDecode(nil, text)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid base64: %w", errInvalid, err)
+	}
+	return b, nil
+}
+
+// Redacted returns the placeholder printed instead of a secret key of the
+// named type, such as "mlkem768.DecapsulationKey".
+func Redacted(typeName string) string {
+	return typeName + "(REDACTED)"
+}
@@ -0,0 +1,32 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package keytext
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestEncodeDecode(t *testing.T) {
+	errInvalid := errors.New("invalid key")
+	for _, b := range [][]byte{{}, {0xff}, {1, 2}, {1, 2, 3}, bytes.Repeat([]byte{0xfb}, 100)} {
+		text := Encode(b)
+		got, err := Decode(text, errInvalid)
+		if err != nil || !bytes.Equal(got, b) {
+			t.Errorf("Decode(%q): got %x, %v, expected %x", text, got, err, b)
+		}
+	}
+	for _, text := range []string{
+		"AQ",       // missing padding
+		"AQI=AQ==", // padding in the middle
+		"AR==",     // non-zero trailing bits
+		"-_8=",     // URL alphabet
+	} {
+		if _, err := Decode([]byte(text), errInvalid); !errors.Is(err, errInvalid) {
+			t.Errorf("Decode(%q): got %v, expected %v", text, err, errInvalid)
+		}
+	}
+}
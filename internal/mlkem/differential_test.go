@@ -0,0 +1,186 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build go1.26
+
+package mlkem
+
+import (
+	"bytes"
+	"crypto/mlkem"
+	"crypto/mlkem/mlkemtest"
+	"crypto/sha3"
+	"testing"
+)
+
+// This file compares this package byte-for-byte with crypto/mlkem, which
+// replaced it as the implementation of the mlkem768 and mlkem1024 packages with
+// Go 1.26. Since their tests only exercise this package with Go 1.25, this is
+// what keeps it from silently diverging on newer toolchains.
+
+type stdDecapsulationKey interface {
+	Decapsulate(ciphertext []byte) (sharedKey []byte, err error)
+}
+
+type stdParameterSet struct {
+	name        string
+	p           *Parameters
+	newKey      func(seed []byte) (ek []byte, dk stdDecapsulationKey, err error)
+	encapsulate func(ek, m []byte) (c, K []byte, err error)
+}
+
+var stdParameterSets = []stdParameterSet{
+	{
+		"ML-KEM-768", MLKEM768,
+		func(seed []byte) ([]byte, stdDecapsulationKey, error) {
+			dk, err := mlkem.NewDecapsulationKey768(seed)
+			if err != nil {
+				return nil, nil, err
+			}
+			return dk.EncapsulationKey().Bytes(), dk, nil
+		},
+		func(ek, m []byte) ([]byte, []byte, error) {
+			k, err := mlkem.NewEncapsulationKey768(ek)
+			if err != nil {
+				return nil, nil, err
+			}
+			K, c, err := mlkemtest.Encapsulate768(k, m)
+			return c, K, err
+		},
+	},
+	{
+		"ML-KEM-1024", MLKEM1024,
+		func(seed []byte) ([]byte, stdDecapsulationKey, error) {
+			dk, err := mlkem.NewDecapsulationKey1024(seed)
+			if err != nil {
+				return nil, nil, err
+			}
+			return dk.EncapsulationKey().Bytes(), dk, nil
+		},
+		func(ek, m []byte) ([]byte, []byte, error) {
+			k, err := mlkem.NewEncapsulationKey1024(ek)
+			if err != nil {
+				return nil, nil, err
+			}
+			K, c, err := mlkemtest.Encapsulate1024(k, m)
+			return c, K, err
+		},
+	},
+}
+
+func TestDifferential(t *testing.T) {
+	n := 1000
+	if testing.Short() {
+		n = 50
+	}
+	for _, ps := range stdParameterSets {
+		t.Run(ps.name, func(t *testing.T) {
+			s := sha3.NewSHAKE128()
+			seed := make([]byte, SeedSize)
+			m := make([]byte, 32)
+			c := make([]byte, ps.p.CiphertextSize())
+			ek := make([]byte, ps.p.EncapsulationKeySize())
+			for i := range n {
+				s.Read(seed)
+				s.Read(m)
+				s.Read(c)
+				s.Read(ek)
+				// Random encapsulation keys almost always fail the modulus
+				// check, so reduce the coefficients of half of them.
+				if i%2 == 1 {
+					reduceEncapsulationKey(ps.p, ek)
+				}
+				differential(t, ps, seed, m, c, ek)
+			}
+		})
+	}
+}
+
+// reduceEncapsulationKey reduces in place the coefficients of the encoded
+// polynomials in ek.
+func reduceEncapsulationKey(p *Parameters, ek []byte) {
+	for i := range p.k {
+		b := ek[i*encodingSize12 : (i+1)*encodingSize12]
+		var f ringElement
+		for j := 0; j < n; j += 2 {
+			d := uint32(b[j/2*3]) | uint32(b[j/2*3+1])<<8 | uint32(b[j/2*3+2])<<16
+			f[j] = fieldElement(d & 0xfff % q)
+			f[j+1] = fieldElement(d >> 12 % q)
+		}
+		polyByteEncode(b[:0], f)
+	}
+}
+
+// FuzzDifferential compares key generation, encapsulation, and decapsulation
+// of valid and arbitrary ciphertexts, and parsing of arbitrary encapsulation
+// keys, for keys and messages derived from entropy.
+func FuzzDifferential(f *testing.F) {
+	f.Add([]byte("key"), make([]byte, MLKEM768.CiphertextSize()), make([]byte, MLKEM768.EncapsulationKeySize()))
+	f.Add([]byte("key"), make([]byte, MLKEM1024.CiphertextSize()), make([]byte, MLKEM1024.EncapsulationKeySize()))
+	f.Add([]byte("key"), bytes.Repeat([]byte{0xff}, MLKEM768.CiphertextSize()), bytes.Repeat([]byte{0xff}, MLKEM768.EncapsulationKeySize()))
+	f.Fuzz(func(t *testing.T, entropy, c, ek []byte) {
+		s := sha3.NewSHAKE128()
+		s.Write(entropy)
+		seed := make([]byte, SeedSize)
+		s.Read(seed)
+		m := make([]byte, 32)
+		s.Read(m)
+		for _, ps := range stdParameterSets {
+			differential(t, ps, seed, m, c, ek)
+		}
+	})
+}
+
+// differential checks that this package and crypto/mlkem produce the same
+// outputs for the key derived from seed, for the encapsulation of m, for the
+// decapsulation of c, and for the encapsulation of m to the arbitrary ek.
+func differential(t *testing.T, ps stdParameterSet, seed, m, c, ek []byte) {
+	t.Helper()
+	name := ps.name
+
+	dk, err := NewKeyFromSeed(nil, ps.p, seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdEK, stdDK, err := ps.newKey(seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	myEK := dk.EncapsulationKey().Bytes()
+	if !bytes.Equal(myEK, stdEK) {
+		t.Fatalf("%s: seed %x: ek: got %x, crypto/mlkem %x", name, seed, myEK, stdEK)
+	}
+
+	myC, myK, err := EncapsulateDerand(nil, ps.p, myEK, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdC, stdK, err := ps.encapsulate(stdEK, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(myC, stdC) || !bytes.Equal(myK, stdK) {
+		t.Fatalf("%s: seed %x, m %x: got %x, %x; crypto/mlkem %x, %x", name, seed, m, myC, myK, stdC, stdK)
+	}
+
+	for _, c := range [][]byte{myC, c} {
+		myK, myErr := Decapsulate(dk, c)
+		stdK, stdErr := stdDK.Decapsulate(c)
+		if (myErr == nil) != (stdErr == nil) {
+			t.Fatalf("%s: seed %x, c %x: got error %v, crypto/mlkem %v", name, seed, c, myErr, stdErr)
+		}
+		if !bytes.Equal(myK, stdK) {
+			t.Fatalf("%s: seed %x, c %x: got %x, crypto/mlkem %x", name, seed, c, myK, stdK)
+		}
+	}
+
+	myC, myK, myErr := EncapsulateDerand(nil, ps.p, ek, m)
+	stdC, stdK, stdErr := ps.encapsulate(ek, m)
+	if (myErr == nil) != (stdErr == nil) {
+		t.Fatalf("%s: ek %x: got error %v, crypto/mlkem %v", name, ek, myErr, stdErr)
+	}
+	if !bytes.Equal(myC, stdC) || !bytes.Equal(myK, stdK) {
+		t.Fatalf("%s: ek %x, m %x: got %x, %x; crypto/mlkem %x, %x", name, ek, m, myC, myK, stdC, stdK)
+	}
+}
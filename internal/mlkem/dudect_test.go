@@ -0,0 +1,236 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mlkem
+
+import (
+	"crypto/rand"
+	"flag"
+	"fmt"
+	"math"
+	mathrand "math/rand/v2"
+	"slices"
+	"testing"
+	"time"
+)
+
+// The tests in this file are statistical timing tests in the style of dudect
+// (Reparaz, Balasch, and Verbauwhede, "Dude, is my code constant time?").
+//
+// Each test measures an operation on inputs from two classes, chosen at random
+// for each measurement, and runs Welch's t-test on the two timing
+// distributions, both on all measurements and on measurements cropped at
+// various percentiles, to remove the long tail caused by interrupts and
+// scheduling. A large t statistic means the timing depends on the class. Crops
+// with fewer than dudectMinSamples measurements of either class are ignored.
+//
+// They are slow and sensitive to system noise, so they only run with
+//
+//	go test -run ConstantTime -dudect 1000000
+//
+// and are best run on an otherwise idle machine.
+
+var dudectFlag = flag.Int("dudect", 0, "run the constant-time tests with `n` measurements")
+
+const (
+	// dudectFailThreshold is the t statistic above which dudect considers an
+	// operation definitely not constant time.
+	dudectFailThreshold = 10
+	// dudectWarnThreshold is the t statistic above which dudect considers an
+	// operation probably not constant time.
+	dudectWarnThreshold = 4.5
+
+	dudectBatchSize   = 10000
+	dudectPercentiles = 100
+
+	// dudectMinSamples is the number of measurements of each class that a
+	// cropped test needs for its t statistic to be considered. Tests cropped
+	// at the lowest percentiles hold only a few measurements, and their t
+	// statistic is too noisy to be compared to the thresholds.
+	dudectMinSamples = 1000
+)
+
+func skipUnlessDudect(t *testing.T) {
+	if *dudectFlag == 0 {
+		t.Skip("constant-time tests only run with -dudect")
+	}
+}
+
+// welch accumulates the mean and variance of two classes of measurements with
+// Welford's online algorithm.
+type welch struct {
+	n, mean, m2 [2]float64
+}
+
+func (w *welch) push(class int, x float64) {
+	w.n[class]++
+	delta := x - w.mean[class]
+	w.mean[class] += delta / w.n[class]
+	w.m2[class] += delta * (x - w.mean[class])
+}
+
+// t returns the Welch's t statistic of the two classes.
+func (w *welch) t() float64 {
+	v0 := w.m2[0] / (w.n[0] - 1)
+	v1 := w.m2[1] / (w.n[1] - 1)
+	return (w.mean[0] - w.mean[1]) / math.Sqrt(v0/w.n[0]+v1/w.n[1])
+}
+
+// dudect measures n runs of op, each on an input of class 0 or 1 returned by
+// prepare, and fails if the timings of the two classes are distinguishable.
+// prepare is called outside the timed section.
+func dudect[T any](t *testing.T, n int, prepare func(class int) T, op func(T)) {
+	n = max(n, dudectBatchSize*2)
+
+	classes := make([]int, dudectBatchSize)
+	inputs := make([]T, dudectBatchSize)
+	timings := make([]float64, dudectBatchSize)
+
+	// tests[0] is on all measurements, and tests[i+1] is on measurements below
+	// thresholds[i], which are set from the first batch.
+	var thresholds []float64
+	tests := make([]welch, 1+dudectPercentiles)
+
+	for done := 0; done < n; done += dudectBatchSize {
+		for i := range classes {
+			classes[i] = mathrand.IntN(2)
+			inputs[i] = prepare(classes[i])
+		}
+		for i := range inputs {
+			start := time.Now()
+			op(inputs[i])
+			timings[i] = float64(time.Since(start))
+		}
+
+		if thresholds == nil {
+			// Like dudect, discard the first batch, and use it to pick the
+			// cropping thresholds, more densely towards the tail.
+			sorted := slices.Sorted(slices.Values(timings))
+			for i := range dudectPercentiles {
+				p := 1 - math.Pow(0.5, 10*float64(i+1)/dudectPercentiles)
+				thresholds = append(thresholds, sorted[int(p*float64(len(sorted)))])
+			}
+			continue
+		}
+		for i, x := range timings {
+			tests[0].push(classes[i], x)
+			for j, th := range thresholds {
+				if x < th {
+					tests[j+1].push(classes[i], x)
+				}
+			}
+		}
+	}
+
+	maxT, maxI := 0.0, 0
+	for i := range tests {
+		if tests[i].n[0] < dudectMinSamples || tests[i].n[1] < dudectMinSamples {
+			continue
+		}
+		if tt := math.Abs(tests[i].t()); tt > maxT {
+			maxT, maxI = tt, i
+		}
+	}
+	w := tests[maxI]
+	t.Logf("%d measurements: t = %.2f on all measurements, max |t| = %.2f on %d measurements (%d and %d per class, means %.0fns and %.0fns)",
+		int(tests[0].n[0]+tests[0].n[1]), tests[0].t(), maxT, int(w.n[0]+w.n[1]), int(w.n[0]), int(w.n[1]), w.mean[0], w.mean[1])
+	switch {
+	case maxT > dudectFailThreshold:
+		t.Errorf("timing depends on the input class: max |t| = %.2f > %d", maxT, dudectFailThreshold)
+	case maxT > dudectWarnThreshold:
+		t.Logf("timing might depend on the input class: max |t| = %.2f > %.1f", maxT, dudectWarnThreshold)
+	}
+}
+
+// TestConstantTimeDecapsulate compares the decapsulation of valid ciphertexts
+// with that of random ciphertexts, which are implicitly rejected.
+func TestConstantTimeDecapsulate(t *testing.T) {
+	skipUnlessDudect(t)
+	for _, ps := range parameterSets {
+		t.Run(ps.name, func(t *testing.T) {
+			dk, err := GenerateKey(nil, ps.p)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ek := dk.EncapsulationKey()
+			var K [SharedKeySize]byte
+			dudect(t, *dudectFlag, func(class int) []byte {
+				if class == 0 {
+					c, _ := ek.Encapsulate(nil)
+					return c
+				}
+				c := make([]byte, ps.p.CiphertextSize())
+				rand.Read(c)
+				return c
+			}, func(c []byte) {
+				kemDecaps(&K, dk, c)
+			})
+		})
+	}
+}
+
+// TestConstantTimeDecapsulationKey compares the decapsulation of random
+// ciphertexts with a fixed key with that with random keys.
+func TestConstantTimeDecapsulationKey(t *testing.T) {
+	skipUnlessDudect(t)
+	for _, ps := range parameterSets {
+		t.Run(ps.name, func(t *testing.T) {
+			// Both classes pick from a pool of the same size, so that they are
+			// equally likely to be in cache. The fixed pool holds copies of
+			// the same key.
+			fixed, err := GenerateKey(nil, ps.p)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var pools [2][256]*DecapsulationKey
+			for i := range pools[0] {
+				k := *fixed
+				pools[0][i] = &k
+				if pools[1][i], err = GenerateKey(nil, ps.p); err != nil {
+					t.Fatal(err)
+				}
+			}
+			type input struct {
+				dk *DecapsulationKey
+				c  []byte
+			}
+			var K [SharedKeySize]byte
+			dudect(t, *dudectFlag, func(class int) input {
+				in := input{
+					dk: pools[class][mathrand.IntN(len(pools[class]))],
+					c:  make([]byte, ps.p.CiphertextSize()),
+				}
+				rand.Read(in.c)
+				return in
+			}, func(in input) {
+				kemDecaps(&K, in.dk, in.c)
+			})
+		})
+	}
+}
+
+// TestConstantTimeCompress compares compressing and encoding the zero ring
+// element with compressing and encoding random ones, which exercises the
+// division by q in compress on secret-dependent values.
+func TestConstantTimeCompress(t *testing.T) {
+	skipUnlessDudect(t)
+	for _, d := range []uint8{1, 4, 5, 10, 11} {
+		t.Run(fmt.Sprintf("d=%d", d), func(t *testing.T) {
+			b := make([]byte, 0, encodingSize(12))
+			dudect(t, *dudectFlag, func(class int) *ringElement {
+				// Both classes are freshly allocated, so that they are
+				// equally likely to be in cache.
+				f := new(ringElement)
+				if class == 1 {
+					for i := range f {
+						f[i] = fieldReduce(mathrand.Uint32N(q * q))
+					}
+				}
+				return f
+			}, func(f *ringElement) {
+				ringCompressAndEncode(b[:0], *f, d)
+			})
+		})
+	}
+}
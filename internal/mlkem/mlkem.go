@@ -0,0 +1,640 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package mlkem implements ML-KEM, as specified in NIST FIPS 203, for all three
+// parameter sets.
+//
+// It is the pure Go implementation behind the mlkem512 package, and behind the
+// mlkem768 and mlkem1024 packages on Go 1.25, before they became wrappers
+// around crypto/mlkem.
+package mlkem
+
+import (
+	"crypto/rand"
+	"crypto/sha3"
+	"crypto/subtle"
+	"errors"
+)
+
+const (
+	// ML-KEM global constants.
+	n = 256
+	q = 3329
+
+	log2q = 12
+
+	// maxK is the largest module dimension, used to size fixed arrays so that
+	// all parameter sets can share the same types without allocating.
+	maxK = 4
+
+	// encodingSize12 is the byte size of a ringElement or nttElement encoded
+	// by ByteEncode₁₂ (FIPS 203, Algorithm 5).
+	encodingSize12 = n * log2q / 8
+	encodingSize1  = n * 1 / 8
+
+	messageSize = encodingSize1
+
+	maxCiphertextSize       = 1568
+	maxEncapsulationKeySize = 1568
+
+	SharedKeySize = 32
+	SeedSize      = 32 + 32
+)
+
+// Errors returned by the input checks of FIPS 203, Sections 7.2 and 7.3.
+var (
+	// ErrKeyLength is returned for keys of the wrong length (the "type check").
+	ErrKeyLength = errors.New("mlkem: invalid key length")
+
+	// ErrKeyModulus is returned for keys with coefficients that are not
+	// reduced modulo q (the "modulus check").
+	ErrKeyModulus = errors.New("mlkem: key coefficients are not reduced")
+
+	// ErrKeyHash is returned for expanded decapsulation keys where the
+	// embedded H(ek) doesn't match ek (the "hash check").
+	ErrKeyHash = errors.New("mlkem: inconsistent H(ek) in decapsulation key")
+)
+
+// Parameters is an ML-KEM parameter set, according to FIPS 203, Section 8.
+type Parameters struct {
+	k      int   // module dimension
+	η1, η2 int   // CBD parameters for s, e, y and for e1, e2
+	du, dv uint8 // compression widths for u and v
+}
+
+var (
+	// MLKEM512 is the ML-KEM-512 parameter set.
+	MLKEM512 = &Parameters{k: 2, η1: 3, η2: 2, du: 10, dv: 4}
+
+	// MLKEM768 is the ML-KEM-768 parameter set.
+	MLKEM768 = &Parameters{k: 3, η1: 2, η2: 2, du: 10, dv: 4}
+
+	// MLKEM1024 is the ML-KEM-1024 parameter set.
+	MLKEM1024 = &Parameters{k: 4, η1: 2, η2: 2, du: 11, dv: 5}
+)
+
+// CiphertextSize returns the size of a ciphertext in bytes.
+func (p *Parameters) CiphertextSize() int {
+	return p.k*encodingSize(p.du) + encodingSize(p.dv)
+}
+
+// EncapsulationKeySize returns the size of an encapsulation key in bytes.
+func (p *Parameters) EncapsulationKeySize() int {
+	return p.k*encodingSize12 + 32
+}
+
+// ExpandedDecapsulationKeySize returns the size of a decapsulation key in the
+// expanded form of FIPS 203, Algorithm 16, in bytes.
+func (p *Parameters) ExpandedDecapsulationKeySize() int {
+	return p.k*encodingSize12 + p.EncapsulationKeySize() + 32 + 32
+}
+
+// encodingSize returns the byte size of a ringElement encoded by ByteEncode_d
+// (FIPS 203, Algorithm 5).
+func encodingSize(d uint8) int {
+	return n * int(d) / 8
+}
+
+// A DecapsulationKey is the secret key used to decapsulate a shared key from a
+// ciphertext. It includes various precomputed values.
+type DecapsulationKey struct {
+	p *Parameters
+
+	d, z [32]byte // decapsulation key seed
+
+	// noSeed is set if dk was parsed from its expanded form, in which case d is
+	// not known and Bytes can't be used.
+	noSeed bool
+
+	ρ [32]byte // sampleNTT seed for A, stored for the encapsulation key
+	h [32]byte // H(ek), stored for ML-KEM.Decaps_internal
+
+	encryptionKey
+	decryptionKey
+}
+
+// Parameters returns the parameter set of the decapsulation key.
+func (dk *DecapsulationKey) Parameters() *Parameters {
+	return dk.p
+}
+
+// Bytes returns the decapsulation key as a 64-byte seed in the "d || z" form.
+//
+// Bytes panics if dk was created by [NewKeyFromExpanded], as the seed is not
+// known in that case.
+func (dk *DecapsulationKey) Bytes() []byte {
+	if dk.noSeed {
+		panic("mlkem: seed is not available for a key parsed from its expanded form")
+	}
+	var b [SeedSize]byte
+	copy(b[:], dk.d[:])
+	copy(b[32:], dk.z[:])
+	return b[:]
+}
+
+// HasSeed reports whether the seed of dk is known, which is the case unless dk
+// was created by [NewKeyFromExpanded].
+func (dk *DecapsulationKey) HasSeed() bool {
+	return !dk.noSeed
+}
+
+// ExpandedBytes returns the decapsulation key in the expanded form of FIPS 203,
+// Algorithm 16, "dk_PKE || ek || H(ek) || z".
+func (dk *DecapsulationKey) ExpandedBytes() []byte {
+	// The actual logic is in a separate function to outline this allocation.
+	b := make([]byte, 0, dk.p.ExpandedDecapsulationKeySize())
+	return dk.expandedBytes(b)
+}
+
+func (dk *DecapsulationKey) expandedBytes(b []byte) []byte {
+	// ByteEncode₁₂(s)
+	for i := range dk.p.k {
+		b = polyByteEncode(b, dk.s[i])
+	}
+
+	// ByteEncode₁₂(t) || ρ
+	b = encodeEK(b, dk.p, &dk.encryptionKey, &dk.ρ)
+
+	// H(ek) || z
+	b = append(b, dk.h[:]...)
+	b = append(b, dk.z[:]...)
+
+	return b
+}
+
+// EncapsulationKey returns the public encapsulation key necessary to produce
+// ciphertexts.
+func (dk *DecapsulationKey) EncapsulationKey() *EncapsulationKey {
+	return &EncapsulationKey{
+		p:             dk.p,
+		ρ:             dk.ρ,
+		h:             dk.h,
+		encryptionKey: dk.encryptionKey,
+	}
+}
+
+// An EncapsulationKey is the public key used to produce ciphertexts to be
+// decapsulated by the corresponding DecapsulationKey. It includes the parsed t
+// and the expanded matrix A, so it can be reused for many encapsulations.
+type EncapsulationKey struct {
+	p *Parameters
+
+	ρ [32]byte // sampleNTT seed for A
+	h [32]byte // H(ek)
+
+	encryptionKey
+}
+
+// Parameters returns the parameter set of the encapsulation key.
+func (ek *EncapsulationKey) Parameters() *Parameters {
+	return ek.p
+}
+
+// Bytes returns the encapsulation key as a byte slice.
+func (ek *EncapsulationKey) Bytes() []byte {
+	// The actual logic is in a separate function to outline this allocation.
+	b := make([]byte, 0, ek.p.EncapsulationKeySize())
+	return ek.bytes(b)
+}
+
+func (ek *EncapsulationKey) bytes(b []byte) []byte {
+	return encodeEK(b, ek.p, &ek.encryptionKey, &ek.ρ)
+}
+
+// encodeEK appends the encoding of an encryption key to b.
+func encodeEK(b []byte, p *Parameters, ex *encryptionKey, ρ *[32]byte) []byte {
+	for i := range p.k {
+		b = polyByteEncode(b, ex.t[i])
+	}
+	b = append(b, ρ[:]...)
+	return b
+}
+
+// encryptionKey is the parsed and expanded form of a PKE encryption key.
+//
+// Only the first k elements of t and the first k * k elements of a are used.
+type encryptionKey struct {
+	t [maxK]nttElement        // ByteDecode₁₂(ek[:384k])
+	a [maxK * maxK]nttElement // A[i*k+j] = sampleNTT(ρ, j, i)
+}
+
+// decryptionKey is the parsed and expanded form of a PKE decryption key.
+//
+// Only the first k elements of s are used.
+type decryptionKey struct {
+	s [maxK]nttElement // ByteDecode₁₂(dk[:384k])
+}
+
+// GenerateKey generates a new decapsulation key, drawing random bytes from
+// crypto/rand. The decapsulation key must be kept secret.
+//
+// If dk is not nil, it is used to store the result, allowing callers to
+// outline the allocation.
+func GenerateKey(dk *DecapsulationKey, p *Parameters) (*DecapsulationKey, error) {
+	var d [32]byte
+	if _, err := rand.Read(d[:]); err != nil {
+		return nil, errors.New("mlkem: crypto/rand Read failed: " + err.Error())
+	}
+	var z [32]byte
+	if _, err := rand.Read(z[:]); err != nil {
+		return nil, errors.New("mlkem: crypto/rand Read failed: " + err.Error())
+	}
+	return kemKeyGen(dk, p, &d, &z), nil
+}
+
+// NewKeyFromSeed deterministically generates a decapsulation key from a 64-byte
+// seed in the "d || z" form. The seed must be uniformly random.
+//
+// If dk is not nil, it is used to store the result, allowing callers to
+// outline the allocation.
+func NewKeyFromSeed(dk *DecapsulationKey, p *Parameters, seed []byte) (*DecapsulationKey, error) {
+	if len(seed) != SeedSize {
+		return nil, errors.New("mlkem: invalid seed length")
+	}
+	d := (*[32]byte)(seed[:32])
+	z := (*[32]byte)(seed[32:])
+	return kemKeyGen(dk, p, d, z), nil
+}
+
+// NewKeyFromExpanded parses a decapsulation key from the expanded form of FIPS
+// 203, Algorithm 16, "dk_PKE || ek || H(ek) || z". If the key is not valid,
+// NewKeyFromExpanded returns an error.
+//
+// The seed is not part of the expanded form, so Bytes must not be called on the
+// returned key.
+//
+// If dk is not nil, it is used to store the result, allowing callers to
+// outline the allocation.
+func NewKeyFromExpanded(dk *DecapsulationKey, p *Parameters, b []byte) (*DecapsulationKey, error) {
+	if len(b) != p.ExpandedDecapsulationKeySize() {
+		return nil, ErrKeyLength
+	}
+	if dk == nil {
+		dk = &DecapsulationKey{}
+	}
+	k := p.k
+
+	var s [maxK]nttElement
+	for i := range k {
+		var err error
+		s[i], err = polyByteDecode[nttElement](b[:encodingSize12])
+		if err != nil {
+			return nil, ErrKeyModulus
+		}
+		b = b[encodingSize12:]
+	}
+
+	var ek EncapsulationKey
+	if err := parseEK(&ek, p, b[:p.EncapsulationKeySize()]); err != nil {
+		return nil, err
+	}
+	b = b[p.EncapsulationKeySize():]
+
+	// The hash check (step 3 of the decapsulation key check from FIPS 203,
+	// Section 7.3).
+	if [32]byte(b[:32]) != ek.h {
+		return nil, ErrKeyHash
+	}
+	b = b[32:]
+
+	*dk = DecapsulationKey{
+		p:             p,
+		z:             [32]byte(b),
+		noSeed:        true,
+		ρ:             ek.ρ,
+		h:             ek.h,
+		encryptionKey: ek.encryptionKey,
+		decryptionKey: decryptionKey{s: s},
+	}
+	return dk, nil
+}
+
+// kemKeyGen generates a decapsulation key.
+//
+// It implements ML-KEM.KeyGen_internal according to FIPS 203, Algorithm 16, and
+// K-PKE.KeyGen according to FIPS 203, Algorithm 13. The two are merged to save
+// copies and allocations.
+func kemKeyGen(dk *DecapsulationKey, p *Parameters, d, z *[32]byte) *DecapsulationKey {
+	if dk == nil {
+		dk = &DecapsulationKey{}
+	}
+	k := p.k
+	dk.p = p
+	dk.d = *d
+	dk.z = *z
+
+	g := sha3.New512()
+	g.Write(d[:])
+	g.Write([]byte{byte(k)}) // Module dimension as a domain separator.
+	var G [64]byte
+	g.Sum(G[:0])
+	ρ, σ := G[:32], G[32:]
+	dk.ρ = [32]byte(ρ)
+
+	A := &dk.a
+	for i := range byte(k) {
+		for j := range byte(k) {
+			A[int(i)*k+int(j)] = sampleNTT(ρ, j, i)
+		}
+	}
+
+	var N byte
+	s := dk.s[:k]
+	for i := range s {
+		s[i] = ntt(samplePolyCBD(σ, N, p.η1))
+		N++
+	}
+	var e [maxK]nttElement
+	for i := range k {
+		e[i] = ntt(samplePolyCBD(σ, N, p.η1))
+		N++
+	}
+
+	t := dk.t[:k]
+	for i := range t { // t = A ◦ s + e
+		t[i] = e[i]
+		for j := range s {
+			t[i] = polyAdd(t[i], nttMul(A[i*k+j], s[j]))
+		}
+	}
+
+	var ek [maxEncapsulationKeySize]byte
+	dk.h = sha3.Sum256(encodeEK(ek[:0], p, &dk.encryptionKey, &dk.ρ))
+
+	return dk
+}
+
+// Encapsulate generates a shared key and an associated ciphertext from an
+// encapsulation key, drawing random bytes from crypto/rand.
+// If the encapsulation key is not valid, Encapsulate returns an error.
+//
+// The ciphertext is appended to c, which is used to outline the allocation.
+//
+// The shared key must be kept secret.
+func Encapsulate(c []byte, p *Parameters, encapsulationKey []byte) (ciphertext, sharedKey []byte, err error) {
+	var ek EncapsulationKey
+	if _, err := NewEncapsulationKey(&ek, p, encapsulationKey); err != nil {
+		return nil, nil, err
+	}
+	ciphertext, sharedKey = ek.Encapsulate(c)
+	return ciphertext, sharedKey, nil
+}
+
+// EncapsulateDerand works like [Encapsulate] but accepts the random bytes as an
+// input. It should only be used for testing.
+func EncapsulateDerand(c []byte, p *Parameters, encapsulationKey, randomness []byte) (ciphertext, sharedKey []byte, err error) {
+	if len(randomness) != messageSize {
+		return nil, nil, errors.New("mlkem: invalid randomness length")
+	}
+	var ek EncapsulationKey
+	if _, err := NewEncapsulationKey(&ek, p, encapsulationKey); err != nil {
+		return nil, nil, err
+	}
+	K := new([SharedKeySize]byte)
+	ciphertext = kemEncaps(c, K, &ek, (*[messageSize]byte)(randomness))
+	return ciphertext, K[:], nil
+}
+
+// Encapsulate generates a shared key and an associated ciphertext, drawing
+// random bytes from crypto/rand.
+//
+// The ciphertext is appended to c, which is used to outline the allocation.
+//
+// The shared key must be kept secret.
+func (ek *EncapsulationKey) Encapsulate(c []byte) (ciphertext, sharedKey []byte) {
+	K := new([SharedKeySize]byte)
+	ciphertext = ek.EncapsulateTo(c, K)
+	return ciphertext, K[:]
+}
+
+// EncapsulateTo works like [EncapsulationKey.Encapsulate] but writes the shared
+// key to sharedKey. It doesn't allocate if c has enough capacity.
+func (ek *EncapsulationKey) EncapsulateTo(c []byte, sharedKey *[SharedKeySize]byte) (ciphertext []byte) {
+	var m [messageSize]byte
+	rand.Read(m[:]) // crypto/rand.Read never returns an error since Go 1.24.
+	return kemEncaps(c, sharedKey, ek, &m)
+}
+
+// kemEncaps generates a shared key and an associated ciphertext, appending the
+// ciphertext to cc and writing the shared key to K.
+//
+// It implements ML-KEM.Encaps_internal according to FIPS 203, Algorithm 17.
+func kemEncaps(cc []byte, K *[SharedKeySize]byte, ek *EncapsulationKey, m *[messageSize]byte) (c []byte) {
+	g := sha3.New512()
+	g.Write(m[:])
+	g.Write(ek.h[:])
+	var G [64]byte
+	g.Sum(G[:0])
+	copy(K[:], G[:SharedKeySize])
+	r := G[SharedKeySize:]
+	return pkeEncrypt(cc, ek.p, &ek.encryptionKey, m, r)
+}
+
+// NewEncapsulationKey parses an encapsulation key from its encoded form.
+// If the encapsulation key is not valid, NewEncapsulationKey returns an error.
+//
+// If ek is not nil, it is used to store the result, allowing callers to
+// outline the allocation.
+func NewEncapsulationKey(ek *EncapsulationKey, p *Parameters, encapsulationKey []byte) (*EncapsulationKey, error) {
+	if ek == nil {
+		ek = &EncapsulationKey{}
+	}
+	// The modulus check (step 2 of the encapsulation key check from FIPS 203,
+	// Section 7.2) is performed by polyByteDecode in parseEK.
+	if err := parseEK(ek, p, encapsulationKey); err != nil {
+		return nil, err
+	}
+	return ek, nil
+}
+
+// CheckEncapsulationKey performs the encapsulation key checks of FIPS 203,
+// Section 7.2, returning ErrKeyLength or ErrKeyModulus if they fail.
+func CheckEncapsulationKey(p *Parameters, encapsulationKey []byte) error {
+	if len(encapsulationKey) != p.EncapsulationKeySize() {
+		return ErrKeyLength
+	}
+	for i := range p.k {
+		b := encapsulationKey[encodingSize12*i : encodingSize12*(i+1)]
+		if _, err := polyByteDecode[nttElement](b); err != nil {
+			return ErrKeyModulus
+		}
+	}
+	return nil
+}
+
+// CheckExpandedDecapsulationKey performs the decapsulation key checks of FIPS
+// 203, Section 7.3, on a key in the expanded form, returning ErrKeyLength,
+// ErrKeyModulus, or ErrKeyHash if they fail.
+//
+// It is intentionally stricter than Section 7.3, which only checks the length
+// and H(ek): the embedded ek gets the modulus check of Section 7.2, and the
+// coefficients of dk_PKE must be reduced too, since parsing them would
+// otherwise silently change the key.
+func CheckExpandedDecapsulationKey(p *Parameters, b []byte) error {
+	if len(b) != p.ExpandedDecapsulationKeySize() {
+		return ErrKeyLength
+	}
+	for range p.k {
+		if _, err := polyByteDecode[nttElement](b[:encodingSize12]); err != nil {
+			return ErrKeyModulus
+		}
+		b = b[encodingSize12:]
+	}
+	ek := b[:p.EncapsulationKeySize()]
+	if err := CheckEncapsulationKey(p, ek); err != nil {
+		return err
+	}
+	b = b[p.EncapsulationKeySize():]
+	if [32]byte(b[:32]) != sha3.Sum256(ek) {
+		return ErrKeyHash
+	}
+	return nil
+}
+
+// parseEK parses an encryption key from its encoded form.
+//
+// It implements the initial stages of K-PKE.Encrypt according to FIPS 203,
+// Algorithm 14.
+func parseEK(ek *EncapsulationKey, p *Parameters, ekPKE []byte) error {
+	if len(ekPKE) != p.EncapsulationKeySize() {
+		return ErrKeyLength
+	}
+	ek.p = p
+	ek.h = sha3.Sum256(ekPKE)
+	k := p.k
+
+	for i := range k {
+		var err error
+		ek.t[i], err = polyByteDecode[nttElement](ekPKE[:encodingSize12])
+		if err != nil {
+			return ErrKeyModulus
+		}
+		ekPKE = ekPKE[encodingSize12:]
+	}
+	ek.ρ = [32]byte(ekPKE)
+
+	for i := range byte(k) {
+		for j := range byte(k) {
+			ek.a[int(i)*k+int(j)] = sampleNTT(ek.ρ[:], j, i)
+		}
+	}
+
+	return nil
+}
+
+// pkeEncrypt encrypt a plaintext message, appending the ciphertext to cc.
+//
+// It implements K-PKE.Encrypt according to FIPS 203, Algorithm 14, although the
+// computation of t and AT is done in parseEK.
+func pkeEncrypt(cc []byte, p *Parameters, ex *encryptionKey, m *[messageSize]byte, rnd []byte) []byte {
+	k := p.k
+	var N byte
+	var r [maxK]nttElement
+	var e1 [maxK]ringElement
+	for i := range k {
+		r[i] = ntt(samplePolyCBD(rnd, N, p.η1))
+		N++
+	}
+	for i := range k {
+		e1[i] = samplePolyCBD(rnd, N, p.η2)
+		N++
+	}
+	e2 := samplePolyCBD(rnd, N, p.η2)
+
+	var u [maxK]ringElement // NTT⁻¹(AT ◦ r) + e1
+	for i := range k {
+		u[i] = e1[i]
+		for j := range k {
+			// Note that i and j are inverted, as we need the transposed of A.
+			u[i] = polyAdd(u[i], inverseNTT(nttMul(ex.a[j*k+i], r[j])))
+		}
+	}
+
+	μ := ringDecodeAndDecompress1(m)
+
+	var vNTT nttElement // t⊺ ◦ r
+	for i := range k {
+		vNTT = polyAdd(vNTT, nttMul(ex.t[i], r[i]))
+	}
+	v := polyAdd(polyAdd(inverseNTT(vNTT), e2), μ)
+
+	c := cc
+	for _, f := range u[:k] {
+		c = ringCompressAndEncode(c, f, p.du)
+	}
+	c = ringCompressAndEncode(c, v, p.dv)
+
+	return c
+}
+
+// Decapsulate generates a shared key from a ciphertext and a decapsulation key.
+// If the ciphertext is not valid, Decapsulate returns an error.
+//
+// The shared key must be kept secret.
+func Decapsulate(dk *DecapsulationKey, ciphertext []byte) (sharedKey []byte, err error) {
+	K := new([SharedKeySize]byte)
+	if err := DecapsulateTo(K, dk, ciphertext); err != nil {
+		return nil, err
+	}
+	return K[:], nil
+}
+
+// DecapsulateTo works like [Decapsulate] but writes the shared key to
+// sharedKey. It doesn't allocate.
+func DecapsulateTo(sharedKey *[SharedKeySize]byte, dk *DecapsulationKey, ciphertext []byte) error {
+	if len(ciphertext) != dk.p.CiphertextSize() {
+		return errors.New("mlkem: invalid ciphertext length")
+	}
+	// Note that the hash check (step 3 of the decapsulation input check from
+	// FIPS 203, Section 7.3) is foregone as a DecapsulationKey is either validly
+	// generated by ML-KEM.KeyGen_internal, or checked by NewKeyFromExpanded.
+	kemDecaps(sharedKey, dk, ciphertext)
+	return nil
+}
+
+// kemDecaps produces a shared key from a ciphertext, writing it to K.
+//
+// It implements ML-KEM.Decaps_internal according to FIPS 203, Algorithm 18.
+func kemDecaps(K *[SharedKeySize]byte, dk *DecapsulationKey, c []byte) {
+	var m [messageSize]byte
+	pkeDecrypt(&m, dk.p, &dk.decryptionKey, c)
+	g := sha3.New512()
+	g.Write(m[:])
+	g.Write(dk.h[:])
+	var G [64]byte
+	g.Sum(G[:0])
+	Kprime, r := G[:SharedKeySize], G[SharedKeySize:]
+	J := sha3.NewSHAKE256()
+	J.Write(dk.z[:])
+	J.Write(c[:])
+	J.Read(K[:])
+	var cc [maxCiphertextSize]byte
+	c1 := pkeEncrypt(cc[:0], dk.p, &dk.encryptionKey, &m, r)
+
+	subtle.ConstantTimeCopy(subtle.ConstantTimeCompare(c[:], c1), K[:], Kprime)
+}
+
+// pkeDecrypt decrypts a ciphertext into m.
+//
+// It implements K-PKE.Decrypt according to FIPS 203, Algorithm 15,
+// although s is retained from kemKeyGen.
+func pkeDecrypt(m *[messageSize]byte, p *Parameters, dx *decryptionKey, c []byte) {
+	k := p.k
+	var u [maxK]ringElement
+	for i := range k {
+		b := c[encodingSize(p.du)*i : encodingSize(p.du)*(i+1)]
+		u[i] = ringDecodeAndDecompress(b, p.du)
+	}
+
+	b := c[encodingSize(p.du)*k:]
+	v := ringDecodeAndDecompress(b, p.dv)
+
+	var mask nttElement // s⊺ ◦ NTT(u)
+	for i := range k {
+		mask = polyAdd(mask, nttMul(dx.s[i], ntt(u[i])))
+	}
+	w := polySub(v, inverseNTT(mask))
+
+	ringCompressAndEncode1(m[:0], w)
+}
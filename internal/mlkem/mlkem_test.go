@@ -0,0 +1,476 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mlkem
+
+import (
+	"bytes"
+	"crypto/sha3"
+	"encoding/hex"
+	"flag"
+	"testing"
+)
+
+var parameterSets = []struct {
+	name                 string
+	p                    *Parameters
+	ciphertextSize       int
+	encapsulationKeySize int
+	expandedKeySize      int
+}{
+	{"ML-KEM-512", MLKEM512, 768, 800, 1632},
+	{"ML-KEM-768", MLKEM768, 1088, 1184, 2400},
+	{"ML-KEM-1024", MLKEM1024, 1568, 1568, 3168},
+}
+
+func TestConstants(t *testing.T) {
+	for _, ps := range parameterSets {
+		t.Run(ps.name, func(t *testing.T) {
+			if got := ps.p.CiphertextSize(); got != ps.ciphertextSize {
+				t.Errorf("CiphertextSize() = %d, want %d", got, ps.ciphertextSize)
+			}
+			if got := ps.p.EncapsulationKeySize(); got != ps.encapsulationKeySize {
+				t.Errorf("EncapsulationKeySize() = %d, want %d", got, ps.encapsulationKeySize)
+			}
+			if got := ps.p.ExpandedDecapsulationKeySize(); got != ps.expandedKeySize {
+				t.Errorf("ExpandedDecapsulationKeySize() = %d, want %d", got, ps.expandedKeySize)
+			}
+			if got := ps.p.CiphertextSize(); got > maxCiphertextSize {
+				t.Errorf("CiphertextSize() = %d, larger than maxCiphertextSize", got)
+			}
+			if got := ps.p.EncapsulationKeySize(); got > maxEncapsulationKeySize {
+				t.Errorf("EncapsulationKeySize() = %d, larger than maxEncapsulationKeySize", got)
+			}
+		})
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, ps := range parameterSets {
+		t.Run(ps.name, func(t *testing.T) {
+			dk, err := GenerateKey(nil, ps.p)
+			if err != nil {
+				t.Fatal(err)
+			}
+			c, Ke, err := Encapsulate(nil, ps.p, dk.EncapsulationKey().Bytes())
+			if err != nil {
+				t.Fatal(err)
+			}
+			Kd, err := Decapsulate(dk, c)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(Ke, Kd) {
+				t.Fail()
+			}
+
+			dk1, err := GenerateKey(nil, ps.p)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if bytes.Equal(dk.EncapsulationKey().Bytes(), dk1.EncapsulationKey().Bytes()) {
+				t.Fail()
+			}
+			if bytes.Equal(dk.Bytes(), dk1.Bytes()) {
+				t.Fail()
+			}
+
+			c1, Ke1, err := Encapsulate(nil, ps.p, dk.EncapsulationKey().Bytes())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if bytes.Equal(c, c1) {
+				t.Fail()
+			}
+			if bytes.Equal(Ke, Ke1) {
+				t.Fail()
+			}
+		})
+	}
+}
+
+func TestExpanded(t *testing.T) {
+	for _, ps := range parameterSets {
+		t.Run(ps.name, func(t *testing.T) {
+			dk, err := GenerateKey(nil, ps.p)
+			if err != nil {
+				t.Fatal(err)
+			}
+			b := dk.ExpandedBytes()
+			if len(b) != ps.expandedKeySize {
+				t.Fatalf("len(ExpandedBytes()) = %d, want %d", len(b), ps.expandedKeySize)
+			}
+			ek := dk.EncapsulationKey().Bytes()
+			if !bytes.Equal(b[ps.expandedKeySize-64-len(ek):ps.expandedKeySize-64], ek) {
+				t.Errorf("expanded key doesn't embed the encapsulation key")
+			}
+
+			dk1, err := NewKeyFromExpanded(nil, ps.p, b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(dk1.ExpandedBytes(), b) {
+				t.Errorf("ExpandedBytes() round-trip mismatch")
+			}
+			if !bytes.Equal(dk1.EncapsulationKey().Bytes(), ek) {
+				t.Errorf("EncapsulationKey() mismatch")
+			}
+
+			c, Ke, err := Encapsulate(nil, ps.p, ek)
+			if err != nil {
+				t.Fatal(err)
+			}
+			Kd, err := Decapsulate(dk1, c)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(Ke, Kd) {
+				t.Errorf("Ke != Kd")
+			}
+			c[0] ^= 1
+			Kd, err = Decapsulate(dk, c)
+			if err != nil {
+				t.Fatal(err)
+			}
+			Kd1, err := Decapsulate(dk1, c)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(Kd, Kd1) {
+				t.Errorf("implicit rejection mismatch")
+			}
+
+			func() {
+				defer func() {
+					if recover() == nil {
+						t.Errorf("expected Bytes to panic")
+					}
+				}()
+				dk1.Bytes()
+			}()
+
+			if _, err := NewKeyFromExpanded(nil, ps.p, b[:len(b)-1]); err == nil {
+				t.Errorf("expected error for short key")
+			}
+			if _, err := NewKeyFromExpanded(nil, ps.p, append(b, 0)); err == nil {
+				t.Errorf("expected error for long key")
+			}
+
+			bad := bytes.Clone(b)
+			bad[len(b)-64] ^= 1 // H(ek)
+			if _, err := NewKeyFromExpanded(nil, ps.p, bad); err == nil {
+				t.Errorf("expected error for bad H(ek)")
+			}
+			bad = bytes.Clone(b)
+			bad[len(b)-64-len(ek)] ^= 1 // ek
+			if _, err := NewKeyFromExpanded(nil, ps.p, bad); err == nil {
+				t.Errorf("expected error for modified ek")
+			}
+			bad = bytes.Clone(b)
+			bad[0], bad[1] = 0xff, 0xff // s[0][0] = 4095
+			if _, err := NewKeyFromExpanded(nil, ps.p, bad); err == nil {
+				t.Errorf("expected error for unreduced s")
+			}
+		})
+	}
+}
+
+func TestCheckKeys(t *testing.T) {
+	for _, ps := range parameterSets {
+		t.Run(ps.name, func(t *testing.T) {
+			dk, err := GenerateKey(nil, ps.p)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ek := dk.EncapsulationKey().Bytes()
+			expanded := dk.ExpandedBytes()
+
+			if err := CheckEncapsulationKey(ps.p, ek); err != nil {
+				t.Errorf("valid encapsulation key: %v", err)
+			}
+			if err := CheckExpandedDecapsulationKey(ps.p, expanded); err != nil {
+				t.Errorf("valid decapsulation key: %v", err)
+			}
+
+			unreduced := func(b []byte, i int) []byte {
+				b = bytes.Clone(b)
+				b[i], b[i+1] = 0xff, 0xff
+				return b
+			}
+			badHash := bytes.Clone(expanded)
+			badHash[len(expanded)-64] ^= 1
+			sOffset := len(expanded) - len(ek) - 64 - encodingSize12
+
+			for _, tt := range []struct {
+				name string
+				err  error
+				got  error
+			}{
+				{"ek length", ErrKeyLength, CheckEncapsulationKey(ps.p, ek[:len(ek)-1])},
+				{"ek modulus", ErrKeyModulus, CheckEncapsulationKey(ps.p, unreduced(ek, 0))},
+				{"dk length", ErrKeyLength, CheckExpandedDecapsulationKey(ps.p, expanded[:len(expanded)-1])},
+				{"dk modulus", ErrKeyModulus, CheckExpandedDecapsulationKey(ps.p, unreduced(expanded, sOffset))},
+				{"dk ek modulus", ErrKeyModulus, CheckExpandedDecapsulationKey(ps.p, unreduced(expanded, sOffset+encodingSize12))},
+				{"dk hash", ErrKeyHash, CheckExpandedDecapsulationKey(ps.p, badHash)},
+			} {
+				if tt.got != tt.err {
+					t.Errorf("%s: got %v, want %v", tt.name, tt.got, tt.err)
+				}
+			}
+
+			if _, err := NewEncapsulationKey(nil, ps.p, unreduced(ek, 0)); err != ErrKeyModulus {
+				t.Errorf("NewEncapsulationKey: got %v, want %v", err, ErrKeyModulus)
+			}
+			if _, err := NewKeyFromExpanded(nil, ps.p, badHash); err != ErrKeyHash {
+				t.Errorf("NewKeyFromExpanded: got %v, want %v", err, ErrKeyHash)
+			}
+		})
+	}
+}
+
+func TestBadLengths(t *testing.T) {
+	for _, ps := range parameterSets {
+		t.Run(ps.name, func(t *testing.T) {
+			dk, err := GenerateKey(nil, ps.p)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ek := dk.EncapsulationKey().Bytes()
+
+			for i := 0; i < len(ek)-1; i++ {
+				if _, _, err := Encapsulate(nil, ps.p, ek[:i]); err == nil {
+					t.Errorf("expected error for ek length %d", i)
+				}
+			}
+			ekLong := ek
+			for i := 0; i < 100; i++ {
+				ekLong = append(ekLong, 0)
+				if _, _, err := Encapsulate(nil, ps.p, ekLong); err == nil {
+					t.Errorf("expected error for ek length %d", len(ekLong))
+				}
+			}
+
+			c, _, err := Encapsulate(nil, ps.p, ek)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for i := 0; i < len(c)-1; i++ {
+				if _, err := Decapsulate(dk, c[:i]); err == nil {
+					t.Errorf("expected error for c length %d", i)
+				}
+			}
+			cLong := c
+			for i := 0; i < 100; i++ {
+				cLong = append(cLong, 0)
+				if _, err := Decapsulate(dk, cLong); err == nil {
+					t.Errorf("expected error for c length %d", len(cLong))
+				}
+			}
+		})
+	}
+}
+
+func TestAllocations(t *testing.T) {
+	for _, ps := range parameterSets {
+		t.Run(ps.name, func(t *testing.T) {
+			dk, err := GenerateKey(nil, ps.p)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ek := dk.EncapsulationKey()
+			var c [maxCiphertextSize]byte
+			var K [SharedKeySize]byte
+			if n := testing.AllocsPerRun(10, func() {
+				ek.EncapsulateTo(c[:0], &K)
+			}); n > 0 {
+				t.Errorf("EncapsulateTo allocated %v times", n)
+			}
+			if n := testing.AllocsPerRun(10, func() {
+				if err := DecapsulateTo(&K, dk, c[:ps.p.CiphertextSize()]); err != nil {
+					t.Fatal(err)
+				}
+			}); n > 0 {
+				t.Errorf("DecapsulateTo allocated %v times", n)
+			}
+		})
+	}
+}
+
+var millionFlag = flag.Bool("million", false, "run the million vector test")
+
+// TestAccumulated accumulates 10k (or 100, or 1M) random vectors and checks the
+// hash of the result, to avoid checking in 150MB of test vectors.
+func TestAccumulated(t *testing.T) {
+	for _, tt := range []struct {
+		name                                 string
+		p                                    *Parameters
+		expected100, expected10k, expected1M string
+	}{
+		{
+			"ML-KEM-512", MLKEM512,
+			"86b1b4703b8ffef6f7f3290c6dbce4ad954498a0673ded401a94828e8c519a59",
+			"e0112db334d4240ca6feed5b0beab1318925edd4ff7d840c2ebe6d61971fc14c",
+			"a3e16deb5dc7fb037a72f0642975a220270bc2adc80718fb97f7de91c3caa6f1",
+		},
+		{
+			"ML-KEM-768", MLKEM768,
+			"1114b1b6699ed191734fa339376afa7e285c9e6acf6ff0177d346696ce564415",
+			"8a518cc63da366322a8e7a818c7a0d63483cb3528d34a4cf42f35d5ad73f22fc",
+			"424bf8f0e8ae99b78d788a6e2e8e9cdaf9773fc0c08a6f433507cb559edfd0f0",
+		},
+		{
+			"ML-KEM-1024", MLKEM1024,
+			"800018fec3e2723f73f1d657fe239b4d5d8782efaade297e8cd448e54cc2ac00",
+			"f1a3925c9cf8538bb104c56efb2f5ecb74cc3df25087460b73f6c873e96bcb6a",
+			"2254e1f80327f405dd4c8c35ab3234c66c4b7b66360324b06caea551235ceab2",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			n, expected := 10000, tt.expected10k
+			if testing.Short() {
+				n, expected = 100, tt.expected100
+			}
+			if *millionFlag {
+				n, expected = 1000000, tt.expected1M
+			}
+			testAccumulated(t, tt.p, n, expected)
+		})
+	}
+}
+
+func testAccumulated(t *testing.T, p *Parameters, n int, expected string) {
+	s := sha3.NewSHAKE128()
+	o := sha3.NewSHAKE128()
+	seed := make([]byte, SeedSize)
+	msg := make([]byte, 32)
+	ct1 := make([]byte, p.CiphertextSize())
+
+	for i := 0; i < n; i++ {
+		s.Read(seed)
+		dk, err := NewKeyFromSeed(nil, p, seed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ek := dk.EncapsulationKey().Bytes()
+		o.Write(ek)
+
+		s.Read(msg)
+		ct, k, err := EncapsulateDerand(nil, p, ek, msg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		o.Write(ct)
+		o.Write(k)
+
+		kk, err := Decapsulate(dk, ct)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(kk, k) {
+			t.Errorf("k: got %x, expected %x", kk, k)
+		}
+
+		s.Read(ct1)
+		k1, err := Decapsulate(dk, ct1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		o.Write(k1)
+	}
+
+	out := make([]byte, 32)
+	o.Read(out)
+	got := hex.EncodeToString(out)
+	if got != expected {
+		t.Errorf("got %s, expected %s", got, expected)
+	}
+}
+
+// FuzzPolyByteDecode checks that polyByteDecode accepts exactly the encodings
+// where all coefficients are reduced, and that it round-trips with
+// polyByteEncode.
+func FuzzPolyByteDecode(f *testing.F) {
+	f.Add(make([]byte, encodingSize12))
+	f.Add(make([]byte, encodingSize12-1))
+	f.Add(bytes.Repeat([]byte{0xff}, encodingSize12))
+	unreduced := make([]byte, encodingSize12)
+	unreduced[0], unreduced[1] = 0x01, 0x0d // q = 3329 = 0xd01
+	f.Add(unreduced)
+	f.Fuzz(func(t *testing.T, b []byte) {
+		p, err := polyByteDecode[ringElement](b)
+		if len(b) != encodingSize12 {
+			if err == nil {
+				t.Fatalf("accepted encoding of length %d", len(b))
+			}
+			return
+		}
+		reduced := true
+		for i := 0; i < n; i += 2 {
+			d := uint32(b[i/2*3]) | uint32(b[i/2*3+1])<<8 | uint32(b[i/2*3+2])<<16
+			if d&0xfff >= q || d>>12 >= q {
+				reduced = false
+			}
+		}
+		if !reduced {
+			if err == nil {
+				t.Fatal("accepted unreduced coefficient")
+			}
+			return
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, c := range p {
+			if c >= q {
+				t.Fatalf("coefficient %d is %d, not reduced", i, c)
+			}
+		}
+		if enc := polyByteEncode(nil, p); !bytes.Equal(enc, b) {
+			t.Errorf("polyByteEncode: got %x, expected %x", enc, b)
+		}
+	})
+}
+
+// FuzzRingDecodeAndDecompress checks that ringDecodeAndDecompress and
+// ringDecodeAndDecompress1 only return reduced coefficients, and that they
+// round-trip with ringCompressAndEncode and ringCompressAndEncode1.
+func FuzzRingDecodeAndDecompress(f *testing.F) {
+	f.Add(make([]byte, encodingSize(11)), uint8(0))
+	f.Add(bytes.Repeat([]byte{0xff}, encodingSize(11)), uint8(0))
+	f.Add(bytes.Repeat([]byte{0xff}, encodingSize(11)), uint8(1))
+	f.Add(bytes.Repeat([]byte{0xff}, encodingSize(11)), uint8(2))
+	f.Add(bytes.Repeat([]byte{0xff}, encodingSize(11)), uint8(3))
+	f.Fuzz(func(t *testing.T, b []byte, d uint8) {
+		checkReduced := func(f ringElement) {
+			t.Helper()
+			for i, c := range f {
+				if c >= q {
+					t.Fatalf("coefficient %d is %d, not reduced", i, c)
+				}
+			}
+		}
+
+		if len(b) >= encodingSize1 {
+			b1 := (*[encodingSize1]byte)(b)
+			f := ringDecodeAndDecompress1(b1)
+			checkReduced(f)
+			if enc := ringCompressAndEncode1(nil, f); !bytes.Equal(enc, b1[:]) {
+				t.Errorf("ringCompressAndEncode1: got %x, expected %x", enc, b1)
+			}
+		}
+
+		// Only the du and dv values of the ML-KEM parameter sets are used.
+		d = []uint8{4, 5, 10, 11}[d%4]
+		if len(b) < encodingSize(d) {
+			return
+		}
+		b = b[:encodingSize(d)]
+		f := ringDecodeAndDecompress(b, d)
+		checkReduced(f)
+		if enc := ringCompressAndEncode(nil, f, d); !bytes.Equal(enc, b) {
+			t.Errorf("ringCompressAndEncode: got %x, expected %x", enc, b)
+		}
+	})
+}
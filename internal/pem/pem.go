@@ -0,0 +1,48 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package pem implements the strict PEM encoding of keys shared by the
+// mlkem768 and xwing packages.
+package pem
+
+import (
+	"bytes"
+	"encoding/pem"
+	"errors"
+	"fmt"
+)
+
+// Encode returns der as a PEM block of the given type, with no headers.
+func Encode(blockType string, der []byte) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+}
+
+// Decode returns the contents of the single PEM block of the given type that
+// makes up data, optionally surrounded by whitespace. Unlike [pem.Decode], it
+// rejects any other data, more than one block, and headers.
+func Decode(data []byte, blockType string) ([]byte, error) {
+	// pem.Decode skips any data before the first valid block, including
+	// malformed blocks, so check there is only whitespace around one block.
+	data = bytes.TrimSpace(data)
+	if !bytes.HasPrefix(data, []byte("-----BEGIN ")) {
+		return nil, errors.New("input doesn't start with a PEM block")
+	}
+	if bytes.Count(data, []byte("-----BEGIN ")) != 1 {
+		return nil, errors.New("input contains more than one PEM block")
+	}
+	block, rest := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("invalid PEM block")
+	}
+	if block.Type != blockType {
+		return nil, fmt.Errorf("unexpected PEM block type %q, expected %q", block.Type, blockType)
+	}
+	if len(block.Headers) != 0 {
+		return nil, errors.New("unexpected PEM headers")
+	}
+	if len(rest) != 0 {
+		return nil, errors.New("trailing data after PEM block")
+	}
+	return block.Bytes, nil
+}
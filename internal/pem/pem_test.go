@@ -0,0 +1,46 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pem
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestDecode(t *testing.T) {
+	der := []byte("not really DER, but any bytes will do")
+	block := Encode("PUBLIC KEY", der)
+	if !bytes.HasPrefix(block, []byte("-----BEGIN PUBLIC KEY-----\n")) {
+		t.Errorf("unexpected PEM:\n%s", block)
+	}
+	for name, data := range map[string][]byte{
+		"block":      block,
+		"whitespace": append([]byte("\n \t"), append(bytes.Clone(block), "\r\n\n"...)...),
+	} {
+		got, err := Decode(data, "PUBLIC KEY")
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+		} else if !bytes.Equal(got, der) {
+			t.Errorf("%s: got %q, expected %q", name, got, der)
+		}
+	}
+
+	withHeaders := bytes.Replace(block, []byte("KEY-----\n"), []byte("KEY-----\nProc-Type: 4,ENCRYPTED\n\n"), 1)
+	for name, data := range map[string][]byte{
+		"wrong type":      Encode("PRIVATE KEY", der),
+		"two blocks":      append(bytes.Clone(block), block...),
+		"trailing data":   append(bytes.Clone(block), "trailing"...),
+		"leading data":    append([]byte("leading\n"), block...),
+		"malformed block": append([]byte("-----BEGIN PUBLIC KEY-----\n"), block...),
+		"no block":        []byte("not PEM"),
+		"empty":           nil,
+		"headers":         withHeaders,
+		"truncated":       block[:len(block)-10],
+	} {
+		if _, err := Decode(data, "PUBLIC KEY"); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mlkem768_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	. "filippo.io/mlkem768"
+)
+
+func TestJWK(t *testing.T) {
+	seed := testSeed()
+	dk, err := NewKeyFromSeed(seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ek, err := NewEncapsulationKey(dk.EncapsulationKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+	b64 := base64.RawURLEncoding
+
+	priv, err := MarshalJWKPrivateKey(dk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var members map[string]string
+	if err := json.Unmarshal(priv, &members); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"kty":  "AKP",
+		"alg":  "ML-KEM-768",
+		"pub":  b64.EncodeToString(ek.Bytes()),
+		"priv": b64.EncodeToString(seed),
+	}
+	if len(members) != len(want) {
+		t.Errorf("got members %v", members)
+	}
+	for k, v := range want {
+		if members[k] != v {
+			t.Errorf("%s: got %q, expected %q", k, members[k], v)
+		}
+	}
+	dk1, err := ParseJWKPrivateKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(dk1.Bytes(), seed) {
+		t.Errorf("ParseJWKPrivateKey: got %x, expected %x", dk1.Bytes(), seed)
+	}
+
+	pub, err := MarshalJWKPublicKey(ek)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ek1, err := ParseJWKPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(ek1.Bytes(), ek.Bytes()) {
+		t.Errorf("ParseJWKPublicKey: got %x, expected %x", ek1.Bytes(), ek.Bytes())
+	}
+	if _, err := ParseJWKPublicKey(priv); err == nil {
+		t.Errorf("ParseJWKPublicKey: expected error for private key")
+	}
+	if _, err := ParseJWKPrivateKey(pub); err == nil {
+		t.Errorf("ParseJWKPrivateKey: expected error for public key")
+	}
+
+	// encoding/json sorts map keys and doesn't add whitespace, like RFC 7638.
+	required, err := json.Marshal(map[string]string{"kty": "AKP", "alg": "ML-KEM-768", "pub": want["pub"]})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := sha256.Sum256(required)
+	if got := JWKThumbprint(ek); got != b64.EncodeToString(h[:]) {
+		t.Errorf("JWKThumbprint: got %s, expected %s", got, b64.EncodeToString(h[:]))
+	}
+
+	otherEK := bytes.Clone(ek.Bytes())
+	otherEK[0] ^= 1
+	for name, change := range map[string]func(m map[string]string){
+		"kty":          func(m map[string]string) { m["kty"] = "OKP" },
+		"alg":          func(m map[string]string) { m["alg"] = "ML-KEM-1024" },
+		"padded priv":  func(m map[string]string) { m["priv"] = base64.URLEncoding.EncodeToString(seed) },
+		"std priv":     func(m map[string]string) { m["priv"] = base64.RawStdEncoding.EncodeToString(seed) },
+		"short priv":   func(m map[string]string) { m["priv"] = b64.EncodeToString(seed[1:]) },
+		"missing pub":  func(m map[string]string) { delete(m, "pub") },
+		"mismatch pub": func(m map[string]string) { m["pub"] = b64.EncodeToString(otherEK) },
+	} {
+		m := make(map[string]string)
+		for k, v := range want {
+			m[k] = v
+		}
+		change(m)
+		data, err := json.Marshal(m)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := ParseJWKPrivateKey(data); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+
+	// encoding/json would match member names case-insensitively and keep the
+	// last of duplicate members, letting parsers disagree on the key.
+	otherPub := `"pub":"` + b64.EncodeToString(otherEK) + `"`
+	for name, data := range map[string]string{
+		"private duplicate pub": strings.Replace(string(priv), "{", "{"+otherPub+",", 1),
+		"private duplicate kty": strings.Replace(string(priv), "{", `{"kty":"AKP",`, 1),
+		"private case kty":      strings.Replace(string(priv), `"kty"`, `"Kty"`, 1),
+		"private case priv":     strings.Replace(string(priv), `"priv"`, `"PRIV"`, 1),
+		"public duplicate pub":  strings.Replace(string(pub), "{", "{"+otherPub+",", 1),
+		"public case pub":       strings.Replace(string(pub), `"pub"`, `"Pub"`, 1),
+		"public case priv":      strings.Replace(string(pub), "}", `,"Priv":"`+want["priv"]+`"}`, 1),
+	} {
+		if _, err := ParseJWKPrivateKey([]byte(data)); err == nil {
+			t.Errorf("%s: ParseJWKPrivateKey: expected error", name)
+		}
+		if _, err := ParseJWKPublicKey([]byte(data)); err == nil {
+			t.Errorf("%s: ParseJWKPublicKey: expected error", name)
+		}
+	}
+
+	dkx, err := NewKeyFromExpanded(dk.ExpandedBytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := MarshalJWKPrivateKey(dkx); err == nil {
+		t.Errorf("MarshalJWKPrivateKey: expected error for expanded key")
+	}
+	dk.Destroy()
+	if _, err := MarshalJWKPrivateKey(dk); !errors.Is(err, ErrKeyDestroyed) {
+		t.Errorf("MarshalJWKPrivateKey: got %v, expected %v", err, ErrKeyDestroyed)
+	}
+}
@@ -0,0 +1,100 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build go1.26
+
+// Package mlkem1024 implements the quantum-resistant key encapsulation method
+// ML-KEM (formerly known as Kyber), as specified in [NIST FIPS 203].
+//
+// Only the ML-KEM-1024 parameter set is provided. Most applications should use
+// the recommended ML-KEM-768 parameter set from [filippo.io/mlkem768] instead.
+//
+// With Go 1.26 and later, this package is just a wrapper around the standard
+// library's [crypto/mlkem] package and [crypto/mlkem/mlkemtest] packages, which
+// provide the same functionality.
+//
+// [NIST FIPS 203]: https://doi.org/10.6028/NIST.FIPS.203
+package mlkem1024
+
+import (
+	"crypto/mlkem"
+	"crypto/mlkem/mlkemtest"
+)
+
+const (
+	CiphertextSize       = mlkem.CiphertextSize1024
+	EncapsulationKeySize = mlkem.EncapsulationKeySize1024
+	SharedKeySize        = mlkem.SharedKeySize
+	SeedSize             = mlkem.SeedSize
+)
+
+// A DecapsulationKey is the secret key used to decapsulate a shared key from a
+// ciphertext. It includes various precomputed values.
+type DecapsulationKey struct {
+	k mlkem.DecapsulationKey1024
+}
+
+// Bytes returns the decapsulation key as a 64-byte seed in the "d || z" form.
+func (dk *DecapsulationKey) Bytes() []byte {
+	return dk.k.Bytes()
+}
+
+// EncapsulationKey returns the public encapsulation key necessary to produce
+// ciphertexts.
+func (dk *DecapsulationKey) EncapsulationKey() []byte {
+	return dk.k.EncapsulationKey().Bytes()
+}
+
+// GenerateKey generates a new decapsulation key, drawing random bytes from
+// crypto/rand. The decapsulation key must be kept secret.
+func GenerateKey() (*DecapsulationKey, error) {
+	k, err := mlkem.GenerateKey1024()
+	if err != nil {
+		return nil, err
+	}
+	return &DecapsulationKey{k: *k}, nil
+}
+
+// NewKeyFromSeed deterministically generates a decapsulation key from a 64-byte
+// seed in the "d || z" form. The seed must be uniformly random.
+func NewKeyFromSeed(seed []byte) (*DecapsulationKey, error) {
+	k, err := mlkem.NewDecapsulationKey1024(seed)
+	if err != nil {
+		return nil, err
+	}
+	return &DecapsulationKey{k: *k}, nil
+}
+
+// Encapsulate generates a shared key and an associated ciphertext from an
+// encapsulation key, drawing random bytes from crypto/rand.
+// If the encapsulation key is not valid, Encapsulate returns an error.
+//
+// The shared key must be kept secret.
+func Encapsulate(encapsulationKey []byte) (ciphertext, sharedKey []byte, err error) {
+	k, err := mlkem.NewEncapsulationKey1024(encapsulationKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	sharedKey, ciphertext = k.Encapsulate()
+	return ciphertext, sharedKey, nil
+}
+
+// EncapsulateDerand works like [Encapsulate] but accepts the random bytes as an
+// input. It should only be used for testing.
+func EncapsulateDerand(encapsulationKey, randomness []byte) (ciphertext, sharedKey []byte, err error) {
+	k, err := mlkem.NewEncapsulationKey1024(encapsulationKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	sharedKey, ciphertext, err = mlkemtest.Encapsulate1024(k, randomness)
+	return ciphertext, sharedKey, err
+}
+
+// Decapsulate generates a shared key from a ciphertext and a decapsulation key.
+// If the ciphertext is not valid, Decapsulate returns an error.
+//
+// The shared key must be kept secret.
+func Decapsulate(dk *DecapsulationKey, ciphertext []byte) (sharedKey []byte, err error) {
+	return dk.k.Decapsulate(ciphertext)
+}
@@ -0,0 +1,97 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !go1.26
+
+package mlkem1024
+
+// This implementation is retained to provide EncapsulateDerand with Go 1.25.
+// The ML-KEM implementation itself is shared by all parameter sets, and lives
+// in the internal/mlkem package.
+
+import "filippo.io/mlkem768/internal/mlkem"
+
+const (
+	CiphertextSize       = 1568
+	EncapsulationKeySize = 1568
+	SharedKeySize        = mlkem.SharedKeySize
+	SeedSize             = mlkem.SeedSize
+)
+
+// A DecapsulationKey is the secret key used to decapsulate a shared key from a
+// ciphertext. It includes various precomputed values.
+type DecapsulationKey struct {
+	k mlkem.DecapsulationKey
+}
+
+// Bytes returns the decapsulation key as a 64-byte seed in the "d || z" form.
+func (dk *DecapsulationKey) Bytes() []byte {
+	return dk.k.Bytes()
+}
+
+// EncapsulationKey returns the public encapsulation key necessary to produce
+// ciphertexts.
+func (dk *DecapsulationKey) EncapsulationKey() []byte {
+	return dk.k.EncapsulationKey().Bytes()
+}
+
+// GenerateKey generates a new decapsulation key, drawing random bytes from
+// crypto/rand. The decapsulation key must be kept secret.
+func GenerateKey() (*DecapsulationKey, error) {
+	// The actual logic is in a separate function to outline this allocation.
+	dk := &DecapsulationKey{}
+	return generateKey(dk)
+}
+
+func generateKey(dk *DecapsulationKey) (*DecapsulationKey, error) {
+	if _, err := mlkem.GenerateKey(&dk.k, mlkem.MLKEM1024); err != nil {
+		return nil, err
+	}
+	return dk, nil
+}
+
+// NewKeyFromSeed deterministically generates a decapsulation key from a 64-byte
+// seed in the "d || z" form. The seed must be uniformly random.
+func NewKeyFromSeed(seed []byte) (*DecapsulationKey, error) {
+	// The actual logic is in a separate function to outline this allocation.
+	dk := &DecapsulationKey{}
+	return newKeyFromSeed(dk, seed)
+}
+
+func newKeyFromSeed(dk *DecapsulationKey, seed []byte) (*DecapsulationKey, error) {
+	if _, err := mlkem.NewKeyFromSeed(&dk.k, mlkem.MLKEM1024, seed); err != nil {
+		return nil, err
+	}
+	return dk, nil
+}
+
+// Encapsulate generates a shared key and an associated ciphertext from an
+// encapsulation key, drawing random bytes from crypto/rand.
+// If the encapsulation key is not valid, Encapsulate returns an error.
+//
+// The shared key must be kept secret.
+func Encapsulate(encapsulationKey []byte) (ciphertext, sharedKey []byte, err error) {
+	// The actual logic is in a separate function to outline this allocation.
+	var cc [CiphertextSize]byte
+	return encapsulate(&cc, encapsulationKey)
+}
+
+func encapsulate(cc *[CiphertextSize]byte, encapsulationKey []byte) (ciphertext, sharedKey []byte, err error) {
+	return mlkem.Encapsulate(cc[:0], mlkem.MLKEM1024, encapsulationKey)
+}
+
+// EncapsulateDerand works like [Encapsulate] but accepts the random bytes as an
+// input. It should only be used for testing.
+func EncapsulateDerand(encapsulationKey, randomness []byte) (ciphertext, sharedKey []byte, err error) {
+	var cc [CiphertextSize]byte
+	return mlkem.EncapsulateDerand(cc[:0], mlkem.MLKEM1024, encapsulationKey, randomness)
+}
+
+// Decapsulate generates a shared key from a ciphertext and a decapsulation key.
+// If the ciphertext is not valid, Decapsulate returns an error.
+//
+// The shared key must be kept secret.
+func Decapsulate(dk *DecapsulationKey, ciphertext []byte) (sharedKey []byte, err error) {
+	return mlkem.Decapsulate(&dk.k, ciphertext)
+}
@@ -0,0 +1,193 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mlkem1024_test
+
+import (
+	"bytes"
+	"testing"
+
+	. "filippo.io/mlkem768/mlkem1024"
+)
+
+// The accumulated and KAT vectors for all parameter sets are checked by
+// internal/mlkem. These tests only cover the behavior of this package's API.
+
+func TestConstants(t *testing.T) {
+	if CiphertextSize != 1568 {
+		t.Errorf("CiphertextSize = %d, want 1568", CiphertextSize)
+	}
+	if EncapsulationKeySize != 1568 {
+		t.Errorf("EncapsulationKeySize = %d, want 1568", EncapsulationKeySize)
+	}
+	if SharedKeySize != 32 {
+		t.Errorf("SharedKeySize = %d, want 32", SharedKeySize)
+	}
+	if SeedSize != 64 {
+		t.Errorf("SeedSize = %d, want 64", SeedSize)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	dk, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, Ke, err := Encapsulate(dk.EncapsulationKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+	Kd, err := Decapsulate(dk, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(Ke, Kd) {
+		t.Fail()
+	}
+
+	dk1, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(dk.EncapsulationKey(), dk1.EncapsulationKey()) {
+		t.Fail()
+	}
+	if bytes.Equal(dk.Bytes(), dk1.Bytes()) {
+		t.Fail()
+	}
+
+	c1, Ke1, err := Encapsulate(dk.EncapsulationKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(c, c1) {
+		t.Fail()
+	}
+	if bytes.Equal(Ke, Ke1) {
+		t.Fail()
+	}
+}
+
+func TestBadLengths(t *testing.T) {
+	dk, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ek := dk.EncapsulationKey()
+
+	for i := 0; i < len(ek)-1; i++ {
+		if _, _, err := Encapsulate(ek[:i]); err == nil {
+			t.Errorf("expected error for ek length %d", i)
+		}
+	}
+	ekLong := ek
+	for i := 0; i < 100; i++ {
+		ekLong = append(ekLong, 0)
+		if _, _, err := Encapsulate(ekLong); err == nil {
+			t.Errorf("expected error for ek length %d", len(ekLong))
+		}
+	}
+
+	c, _, err := Encapsulate(ek)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < len(c)-1; i++ {
+		if _, err := Decapsulate(dk, c[:i]); err == nil {
+			t.Errorf("expected error for c length %d", i)
+		}
+	}
+	cLong := c
+	for i := 0; i < 100; i++ {
+		cLong = append(cLong, 0)
+		if _, err := Decapsulate(dk, cLong); err == nil {
+			t.Errorf("expected error for c length %d", len(cLong))
+		}
+	}
+}
+
+func TestSeedAndDerand(t *testing.T) {
+	seed := make([]byte, SeedSize)
+	seed[0] = 1
+	dk, err := NewKeyFromSeed(seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(dk.Bytes(), seed) {
+		t.Errorf("Bytes() = %x, want %x", dk.Bytes(), seed)
+	}
+	if _, err := NewKeyFromSeed(seed[:SeedSize-1]); err == nil {
+		t.Error("expected error for short seed")
+	}
+	if _, err := NewKeyFromSeed(append(seed, 0)); err == nil {
+		t.Error("expected error for long seed")
+	}
+
+	ek := dk.EncapsulationKey()
+	m := make([]byte, 32)
+	c, K, err := EncapsulateDerand(ek, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c1, K1, err := EncapsulateDerand(ek, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(c, c1) || !bytes.Equal(K, K1) {
+		t.Error("EncapsulateDerand is not deterministic")
+	}
+	Kd, err := Decapsulate(dk, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(K, Kd) {
+		t.Errorf("Decapsulate = %x, want %x", Kd, K)
+	}
+	if _, _, err := EncapsulateDerand(ek, m[:31]); err == nil {
+		t.Error("expected error for short randomness")
+	}
+	if _, _, err := EncapsulateDerand(ek, append(m, 0)); err == nil {
+		t.Error("expected error for long randomness")
+	}
+}
+
+var sink byte
+
+func BenchmarkRoundTrip(b *testing.B) {
+	dk, err := GenerateKey()
+	if err != nil {
+		b.Fatal(err)
+	}
+	ek := dk.EncapsulationKey()
+	c, _, err := Encapsulate(ek)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Run("Alice", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			dkS, err := GenerateKey()
+			if err != nil {
+				b.Fatal(err)
+			}
+			ekS := dkS.EncapsulationKey()
+			sink ^= ekS[0]
+
+			Ks, err := Decapsulate(dk, c)
+			if err != nil {
+				b.Fatal(err)
+			}
+			sink ^= Ks[0]
+		}
+	})
+	b.Run("Bob", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			cS, Ks, err := Encapsulate(ek)
+			if err != nil {
+				b.Fatal(err)
+			}
+			sink ^= cS[0] ^ Ks[0]
+		}
+	})
+}
@@ -11,14 +11,26 @@
 //
 // This package is now just a wrapper around the standard library's
 // [crypto/mlkem] package and [crypto/mlkem/mlkemtest] packages, which provide
-// the same functionality.
+// the same functionality. The only exception is [NewKeyFromExpanded], which
+// relies on the pure Go implementation used with Go 1.25.
+//
+// The COSE encodings, such as [MarshalCOSEPublicKey] and [SealCOSEEncrypt], use
+// a private-use algorithm identifier and a private recipient format. They are
+// not interoperable with other COSE implementations, and are only meant for
+// exchanging keys and messages with other users of this package.
 //
 // [NIST FIPS 203]: https://doi.org/10.6028/NIST.FIPS.203
 package mlkem768
 
 import (
+	"crypto/fips140"
 	"crypto/mlkem"
 	"crypto/mlkem/mlkemtest"
+	"crypto/rand"
+	"errors"
+	"io"
+
+	internalmlkem "filippo.io/mlkem768/internal/mlkem"
 )
 
 const (
@@ -26,25 +38,95 @@ const (
 	EncapsulationKeySize = mlkem.EncapsulationKeySize768
 	SharedKeySize        = mlkem.SharedKeySize
 	SeedSize             = mlkem.SeedSize
+
+	ExpandedDecapsulationKeySize = 2400
 )
 
 // A DecapsulationKey is the secret key used to decapsulate a shared key from a
 // ciphertext. It includes various precomputed values.
+//
+// Keys should be held by pointer, as returned by the constructors. Copying a
+// DecapsulationKey value duplicates the secret key where
+// [DecapsulationKey.Destroy] can't reach it, and only pointers are redacted
+// when formatted by the fmt package.
 type DecapsulationKey struct {
 	k mlkem.DecapsulationKey768
+
+	// x is used instead of k for keys created by NewKeyFromExpanded, which
+	// crypto/mlkem doesn't support.
+	x *internalmlkem.DecapsulationKey
+
+	destroyed bool
 }
 
 // Bytes returns the decapsulation key as a 64-byte seed in the "d || z" form.
+//
+// Bytes panics if dk was created by [NewKeyFromExpanded], as the seed is not
+// known in that case.
 func (dk *DecapsulationKey) Bytes() []byte {
+	dk.checkDestroyed()
+	if dk.x != nil {
+		return dk.x.Bytes()
+	}
 	return dk.k.Bytes()
 }
 
+// hasSeed reports whether Bytes can be used, which is the case unless dk was
+// created by NewKeyFromExpanded.
+func (dk *DecapsulationKey) hasSeed() bool {
+	return dk.x == nil
+}
+
+// ExpandedBytes returns the decapsulation key in the 2400-byte expanded form of
+// FIPS 203, "dk_PKE || ek || H(ek) || z". Prefer [DecapsulationKey.Bytes],
+// which returns the much smaller seed, whenever possible.
+func (dk *DecapsulationKey) ExpandedBytes() []byte {
+	dk.checkDestroyed()
+	if dk.x != nil {
+		return dk.x.ExpandedBytes()
+	}
+	// crypto/mlkem doesn't expose the expanded key, so recompute it from the
+	// seed with the pure Go implementation.
+	x, err := internalmlkem.NewKeyFromSeed(nil, internalmlkem.MLKEM768, dk.k.Bytes())
+	if err != nil {
+		panic("mlkem768: internal error: " + err.Error())
+	}
+	defer func() { *x = internalmlkem.DecapsulationKey{} }()
+	return x.ExpandedBytes()
+}
+
 // EncapsulationKey returns the public encapsulation key necessary to produce
 // ciphertexts.
 func (dk *DecapsulationKey) EncapsulationKey() []byte {
+	dk.checkDestroyed()
+	if dk.x != nil {
+		return dk.x.EncapsulationKey().Bytes()
+	}
 	return dk.k.EncapsulationKey().Bytes()
 }
 
+// Destroy zeroizes the decapsulation key. After Destroy, Decapsulate returns an
+// error, and the methods of dk panic.
+//
+// With Go 1.25, or if dk was created by [NewKeyFromExpanded], Destroy
+// overwrites the key material in place. Otherwise, with Go 1.26 and later, the
+// key material is held by the crypto/mlkem package, which doesn't support
+// zeroization, so Destroy can only drop the reference to it.
+func (dk *DecapsulationKey) Destroy() {
+	dk.k = mlkem.DecapsulationKey768{}
+	if dk.x != nil {
+		*dk.x = internalmlkem.DecapsulationKey{}
+		dk.x = nil
+	}
+	dk.destroyed = true
+}
+
+func (dk *DecapsulationKey) checkDestroyed() {
+	if dk.destroyed {
+		panic(ErrKeyDestroyed)
+	}
+}
+
 // GenerateKey generates a new decapsulation key, drawing random bytes from
 // crypto/rand. The decapsulation key must be kept secret.
 func GenerateKey() (*DecapsulationKey, error) {
@@ -58,6 +140,9 @@ func GenerateKey() (*DecapsulationKey, error) {
 // NewKeyFromSeed deterministically generates a decapsulation key from a 64-byte
 // seed in the "d || z" form. The seed must be uniformly random.
 func NewKeyFromSeed(seed []byte) (*DecapsulationKey, error) {
+	if len(seed) != SeedSize {
+		return nil, ErrInvalidSeed
+	}
 	k, err := mlkem.NewDecapsulationKey768(seed)
 	if err != nil {
 		return nil, err
@@ -65,6 +150,70 @@ func NewKeyFromSeed(seed []byte) (*DecapsulationKey, error) {
 	return &DecapsulationKey{k: *k}, nil
 }
 
+// NewKeyFromExpanded parses a decapsulation key from the 2400-byte expanded
+// form of FIPS 203, "dk_PKE || ek || H(ek) || z", used by some other
+// implementations and hardware modules. If the key is not valid, including if
+// it fails the hash check of FIPS 203, Section 7.3, NewKeyFromExpanded returns
+// an error. It performs the same checks as [ValidateDecapsulationKey], which
+// are stricter than FIPS 203 requires.
+//
+// The seed can't be recovered from the expanded form, so the Bytes method of
+// the returned key panics. Prefer the seed form whenever possible.
+func NewKeyFromExpanded(expanded []byte) (*DecapsulationKey, error) {
+	x, err := internalmlkem.NewKeyFromExpanded(nil, internalmlkem.MLKEM768, expanded)
+	if err != nil {
+		return nil, decapsulationKeyError(expanded, err)
+	}
+	return &DecapsulationKey{x: x}, nil
+}
+
+// GenerateKeyFromReader works like [GenerateKey] but draws random bytes from r
+// instead of crypto/rand. It reads exactly [SeedSize] bytes from r.
+//
+// r must be a cryptographically secure random source, as its output is the
+// decapsulation key seed. In FIPS 140-only mode, r must be crypto/rand.Reader.
+func GenerateKeyFromReader(r io.Reader) (*DecapsulationKey, error) {
+	if fips140.Enforced() && r != rand.Reader {
+		return nil, errors.New("mlkem768: only crypto/rand.Reader is allowed in FIPS 140-only mode")
+	}
+	seed := make([]byte, SeedSize)
+	if _, err := io.ReadFull(r, seed); err != nil {
+		return nil, err
+	}
+	return NewKeyFromSeed(seed)
+}
+
+// An EncapsulationKey is the public key used to produce ciphertexts to be
+// decapsulated by the corresponding [DecapsulationKey]. It includes various
+// precomputed values, so it should be reused for repeated encapsulations.
+type EncapsulationKey struct {
+	k mlkem.EncapsulationKey768
+}
+
+// NewEncapsulationKey parses an encapsulation key from its encoded form.
+// If the encapsulation key is not valid, NewEncapsulationKey returns an error.
+func NewEncapsulationKey(encapsulationKey []byte) (*EncapsulationKey, error) {
+	k, err := mlkem.NewEncapsulationKey768(encapsulationKey)
+	if err != nil {
+		return nil, encapsulationKeyError(encapsulationKey, err)
+	}
+	return &EncapsulationKey{k: *k}, nil
+}
+
+// Bytes returns the encapsulation key as a byte slice.
+func (ek *EncapsulationKey) Bytes() []byte {
+	return ek.k.Bytes()
+}
+
+// Encapsulate generates a shared key and an associated ciphertext, drawing
+// random bytes from crypto/rand.
+//
+// The shared key must be kept secret.
+func (ek *EncapsulationKey) Encapsulate() (ciphertext, sharedKey []byte) {
+	sharedKey, ciphertext = ek.k.Encapsulate()
+	return ciphertext, sharedKey
+}
+
 // Encapsulate generates a shared key and an associated ciphertext from an
 // encapsulation key, drawing random bytes from crypto/rand.
 // If the encapsulation key is not valid, Encapsulate returns an error.
@@ -73,7 +222,7 @@ func NewKeyFromSeed(seed []byte) (*DecapsulationKey, error) {
 func Encapsulate(encapsulationKey []byte) (ciphertext, sharedKey []byte, err error) {
 	k, err := mlkem.NewEncapsulationKey768(encapsulationKey)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, encapsulationKeyError(encapsulationKey, err)
 	}
 	sharedKey, ciphertext = k.Encapsulate()
 	return ciphertext, sharedKey, nil
@@ -81,19 +230,87 @@ func Encapsulate(encapsulationKey []byte) (ciphertext, sharedKey []byte, err err
 
 // EncapsulateDerand works like [Encapsulate] but accepts the random bytes as an
 // input. It should only be used for testing.
+//
+// In FIPS 140-only mode, EncapsulateDerand returns an error.
 func EncapsulateDerand(encapsulationKey, randomness []byte) (ciphertext, sharedKey []byte, err error) {
+	if len(randomness) != 32 {
+		return nil, nil, ErrInvalidRandomness
+	}
 	k, err := mlkem.NewEncapsulationKey768(encapsulationKey)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, encapsulationKeyError(encapsulationKey, err)
 	}
 	sharedKey, ciphertext, err = mlkemtest.Encapsulate768(k, randomness)
 	return ciphertext, sharedKey, err
 }
 
+// EncapsulateWithReader works like [Encapsulate] but draws random bytes from r
+// instead of crypto/rand. It reads exactly 32 bytes from r.
+//
+// r must be a cryptographically secure random source, as its output determines
+// the shared key. In FIPS 140-only mode, r must be crypto/rand.Reader.
+func EncapsulateWithReader(r io.Reader, encapsulationKey []byte) (ciphertext, sharedKey []byte, err error) {
+	if fips140.Enforced() {
+		// Derandomized encapsulation is not allowed in FIPS 140-only mode.
+		if r != rand.Reader {
+			return nil, nil, errors.New("mlkem768: only crypto/rand.Reader is allowed in FIPS 140-only mode")
+		}
+		return Encapsulate(encapsulationKey)
+	}
+	m := make([]byte, 32)
+	if _, err := io.ReadFull(r, m); err != nil {
+		return nil, nil, err
+	}
+	return EncapsulateDerand(encapsulationKey, m)
+}
+
 // Decapsulate generates a shared key from a ciphertext and a decapsulation key.
 // If the ciphertext is not valid, Decapsulate returns an error.
 //
 // The shared key must be kept secret.
 func Decapsulate(dk *DecapsulationKey, ciphertext []byte) (sharedKey []byte, err error) {
+	if dk.destroyed {
+		return nil, ErrKeyDestroyed
+	}
+	if len(ciphertext) != CiphertextSize {
+		return nil, ErrInvalidCiphertextLength
+	}
+	if dk.x != nil {
+		return internalmlkem.Decapsulate(dk.x, ciphertext)
+	}
 	return dk.k.Decapsulate(ciphertext)
 }
+
+// EncapsulateTo works like [EncapsulationKey.Encapsulate] but writes the
+// ciphertext and shared key to the provided arrays.
+//
+// With Go 1.25, EncapsulateTo doesn't allocate. With Go 1.26 and later, the
+// standard library implementation might still allocate internally.
+func EncapsulateTo(ciphertext *[CiphertextSize]byte, sharedKey *[SharedKeySize]byte, ek *EncapsulationKey) {
+	K, c := ek.k.Encapsulate()
+	copy(ciphertext[:], c)
+	copy(sharedKey[:], K)
+}
+
+// DecapsulateTo works like [Decapsulate] but writes the shared key to the
+// provided array.
+//
+// With Go 1.25, DecapsulateTo doesn't allocate. With Go 1.26 and later, the
+// standard library implementation might still allocate internally.
+func DecapsulateTo(sharedKey *[SharedKeySize]byte, dk *DecapsulationKey, ciphertext []byte) error {
+	if dk.destroyed {
+		return ErrKeyDestroyed
+	}
+	if len(ciphertext) != CiphertextSize {
+		return ErrInvalidCiphertextLength
+	}
+	if dk.x != nil {
+		return internalmlkem.DecapsulateTo(sharedKey, dk.x, ciphertext)
+	}
+	K, err := dk.k.Decapsulate(ciphertext)
+	if err != nil {
+		return err
+	}
+	copy(sharedKey[:], K)
+	return nil
+}
@@ -0,0 +1,38 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !go1.26
+
+package mlkem768_test
+
+import (
+	"testing"
+
+	. "filippo.io/mlkem768"
+)
+
+func TestAllocations(t *testing.T) {
+	dk, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ek, err := NewEncapsulationKey(dk.EncapsulationKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var c [CiphertextSize]byte
+	var K [SharedKeySize]byte
+	if n := testing.AllocsPerRun(10, func() {
+		EncapsulateTo(&c, &K, ek)
+	}); n > 0 {
+		t.Errorf("EncapsulateTo allocated %v times", n)
+	}
+	if n := testing.AllocsPerRun(10, func() {
+		if err := DecapsulateTo(&K, dk, c[:]); err != nil {
+			t.Fatal(err)
+		}
+	}); n > 0 {
+		t.Errorf("DecapsulateTo allocated %v times", n)
+	}
+}
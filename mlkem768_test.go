@@ -6,10 +6,15 @@ package mlkem768_test
 
 import (
 	"bytes"
+	"crypto/ecdh"
+	"crypto/rand"
 	"crypto/sha3"
-	_ "embed"
 	"encoding/hex"
+	"errors"
 	"flag"
+	"os"
+	"os/exec"
+	"slices"
 	"testing"
 
 	. "filippo.io/mlkem768"
@@ -70,6 +75,458 @@ func TestRoundTrip(t *testing.T) {
 	}
 }
 
+func TestEncapsulationKey(t *testing.T) {
+	dk, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ek, err := NewEncapsulationKey(dk.EncapsulationKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(ek.Bytes(), dk.EncapsulationKey()) {
+		t.Errorf("ek.Bytes() != dk.EncapsulationKey()")
+	}
+
+	c, Ke := ek.Encapsulate()
+	Kd, err := Decapsulate(dk, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(Ke, Kd) {
+		t.Errorf("Ke != Kd")
+	}
+
+	c1, Ke1 := ek.Encapsulate()
+	if bytes.Equal(c, c1) {
+		t.Errorf("c == c1")
+	}
+	if bytes.Equal(Ke, Ke1) {
+		t.Errorf("Ke == Ke1")
+	}
+
+	b := dk.EncapsulationKey()
+	for i := 0; i < len(b)-1; i++ {
+		if _, err := NewEncapsulationKey(b[:i]); err == nil {
+			t.Errorf("expected error for ek length %d", i)
+		}
+	}
+	if _, err := NewEncapsulationKey(append(b, 0)); err == nil {
+		t.Errorf("expected error for ek length %d", len(b)+1)
+	}
+
+	// An unreduced coefficient must fail the modulus check.
+	b[0], b[1] = 0xff, 0xff
+	if _, err := NewEncapsulationKey(b); err == nil {
+		t.Errorf("expected error for unreduced ek")
+	}
+}
+
+func TestEncapsulateToDecapsulateTo(t *testing.T) {
+	dk, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ek, err := NewEncapsulationKey(dk.EncapsulationKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var c [CiphertextSize]byte
+	var Ke, Kd [SharedKeySize]byte
+	EncapsulateTo(&c, &Ke, ek)
+	if err := DecapsulateTo(&Kd, dk, c[:]); err != nil {
+		t.Fatal(err)
+	}
+	if Ke != Kd {
+		t.Errorf("Ke != Kd")
+	}
+	K, err := Decapsulate(dk, c[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(K, Ke[:]) {
+		t.Errorf("Decapsulate != DecapsulateTo")
+	}
+	if err := DecapsulateTo(&Kd, dk, c[:len(c)-1]); err == nil {
+		t.Errorf("expected error for short ciphertext")
+	}
+}
+
+func TestExpanded(t *testing.T) {
+	seed := make([]byte, SeedSize)
+	for i := range seed {
+		seed[i] = byte(i)
+	}
+	dk, err := NewKeyFromSeed(seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := dk.ExpandedBytes()
+	if len(b) != ExpandedDecapsulationKeySize {
+		t.Fatalf("len(ExpandedBytes()) = %d, want %d", len(b), ExpandedDecapsulationKeySize)
+	}
+	h := sha3.Sum256(b)
+	expected := "1149f17c3c4ac6ab1e3e2d9d8bd0171355ac0fa31bb8855c48ceade874c0864b"
+	if got := hex.EncodeToString(h[:]); got != expected {
+		t.Errorf("ExpandedBytes() hash = %s, want %s", got, expected)
+	}
+
+	dk1, err := NewKeyFromExpanded(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(dk1.ExpandedBytes(), b) {
+		t.Errorf("ExpandedBytes() round-trip mismatch")
+	}
+	if !bytes.Equal(dk1.EncapsulationKey(), dk.EncapsulationKey()) {
+		t.Errorf("EncapsulationKey() mismatch")
+	}
+
+	c, Ke, err := Encapsulate(dk1.EncapsulationKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+	Kd, err := Decapsulate(dk1, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(Ke, Kd) {
+		t.Errorf("Ke != Kd")
+	}
+	var K [SharedKeySize]byte
+	if err := DecapsulateTo(&K, dk1, c); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(Ke, K[:]) {
+		t.Errorf("Ke != K")
+	}
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("expected Bytes to panic")
+			}
+		}()
+		dk1.Bytes()
+	}()
+
+	if _, err := NewKeyFromExpanded(b[:len(b)-1]); err == nil {
+		t.Errorf("expected error for short key")
+	}
+	bad := bytes.Clone(b)
+	bad[len(b)-64] ^= 1 // H(ek)
+	if _, err := NewKeyFromExpanded(bad); err == nil {
+		t.Errorf("expected error for bad H(ek)")
+	}
+	bad = bytes.Clone(b)
+	bad[len(b)-64-EncapsulationKeySize] ^= 1 // ek
+	if _, err := NewKeyFromExpanded(bad); err == nil {
+		t.Errorf("expected error for modified ek")
+	}
+
+	dk1.Destroy()
+	if _, err := Decapsulate(dk1, c); err == nil {
+		t.Errorf("expected error from Decapsulate after Destroy")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	dk, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ek := dk.EncapsulationKey()
+	expanded := dk.ExpandedBytes()
+
+	if err := ValidateEncapsulationKey(ek); err != nil {
+		t.Errorf("valid encapsulation key: %v", err)
+	}
+	if err := ValidateDecapsulationKey(expanded); err != nil {
+		t.Errorf("valid decapsulation key: %v", err)
+	}
+
+	unreduced := func(b []byte, i int) []byte {
+		b = bytes.Clone(b)
+		b[i], b[i+1] = 0xff, 0xff
+		return b
+	}
+	badHash := bytes.Clone(expanded)
+	badHash[len(expanded)-64] ^= 1
+
+	for _, tt := range []struct {
+		name string
+		err  error
+		got  error
+	}{
+		{"ek short", ErrKeyLength, ValidateEncapsulationKey(ek[:len(ek)-1])},
+		{"ek long", ErrKeyLength, ValidateEncapsulationKey(append(ek, 0))},
+		{"ek modulus", ErrKeyModulus, ValidateEncapsulationKey(unreduced(ek, 0))},
+		{"ek last modulus", ErrKeyModulus, ValidateEncapsulationKey(unreduced(ek, len(ek)-34))},
+		{"dk short", ErrKeyLength, ValidateDecapsulationKey(expanded[:len(expanded)-1])},
+		{"dk seed", ErrKeyLength, ValidateDecapsulationKey(dk.Bytes())},
+		{"dk modulus", ErrKeyModulus, ValidateDecapsulationKey(unreduced(expanded, 0))},
+		{"dk ek modulus", ErrKeyModulus, ValidateDecapsulationKey(unreduced(expanded, 1152))},
+		{"dk hash", ErrKeyHash, ValidateDecapsulationKey(badHash)},
+	} {
+		if !errors.Is(tt.got, tt.err) {
+			t.Errorf("%s: got %v, want %v", tt.name, tt.got, tt.err)
+		}
+	}
+}
+
+// TestValidateUnreducedSecret checks that keys with an unreduced dk_PKE are
+// rejected even though they pass the checks of FIPS 203, Section 7.3, which
+// only cover the length and H(ek).
+func TestValidateUnreducedSecret(t *testing.T) {
+	dk, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expanded := dk.ExpandedBytes()
+	for _, i := range []int{0, 1152 - 2} {
+		b := bytes.Clone(expanded)
+		b[i], b[i+1] = 0xff, 0xff
+
+		ek, h := b[1152:1152+EncapsulationKeySize], b[1152+EncapsulationKeySize:][:32]
+		if sum := sha3.Sum256(ek); !bytes.Equal(h, sum[:]) {
+			t.Fatalf("offset %d: H(ek) doesn't match", i)
+		}
+		if err := ValidateDecapsulationKey(b); !errors.Is(err, ErrKeyModulus) {
+			t.Errorf("offset %d: ValidateDecapsulationKey: got %v, want %v", i, err, ErrKeyModulus)
+		}
+		if _, err := NewKeyFromExpanded(b); !errors.Is(err, ErrKeyModulus) {
+			t.Errorf("offset %d: NewKeyFromExpanded: got %v, want %v", i, err, ErrKeyModulus)
+		}
+	}
+}
+
+func TestErrors(t *testing.T) {
+	dk, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ek := dk.EncapsulationKey()
+	unreduced := bytes.Clone(ek)
+	unreduced[0], unreduced[1] = 0xff, 0xff
+	badHash := dk.ExpandedBytes()
+	badHash[len(badHash)-64] ^= 1
+	c, _, err := Encapsulate(ek)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	check := func(name string, got error, want ...error) {
+		t.Helper()
+		for _, want := range want {
+			if !errors.Is(got, want) {
+				t.Errorf("%s: got %v, want %v", name, got, want)
+			}
+		}
+	}
+
+	_, err = NewKeyFromSeed(make([]byte, SeedSize-1))
+	check("NewKeyFromSeed", err, ErrInvalidSeed)
+	_, err = NewKeyFromExpanded(badHash)
+	check("NewKeyFromExpanded", err, ErrInvalidDecapsulationKey, ErrKeyHash)
+	_, err = NewKeyFromExpanded(badHash[1:])
+	check("NewKeyFromExpanded", err, ErrInvalidDecapsulationKey, ErrKeyLength)
+	_, err = NewEncapsulationKey(unreduced)
+	check("NewEncapsulationKey", err, ErrInvalidEncapsulationKey, ErrKeyModulus)
+	_, err = NewEncapsulationKey(ek[1:])
+	check("NewEncapsulationKey", err, ErrInvalidEncapsulationKey, ErrKeyLength)
+	_, _, err = Encapsulate(unreduced)
+	check("Encapsulate", err, ErrInvalidEncapsulationKey, ErrKeyModulus)
+	_, _, err = EncapsulateDerand(unreduced, make([]byte, 32))
+	check("EncapsulateDerand", err, ErrInvalidEncapsulationKey, ErrKeyModulus)
+	_, _, err = EncapsulateDerand(ek, make([]byte, 31))
+	check("EncapsulateDerand", err, ErrInvalidRandomness)
+	_, err = Decapsulate(dk, c[1:])
+	check("Decapsulate", err, ErrInvalidCiphertextLength)
+	var K [SharedKeySize]byte
+	err = DecapsulateTo(&K, dk, c[1:])
+	check("DecapsulateTo", err, ErrInvalidCiphertextLength)
+	dk.Destroy()
+	_, err = Decapsulate(dk, c)
+	check("Decapsulate", err, ErrKeyDestroyed)
+}
+
+func TestGenerateKeyWithPCT(t *testing.T) {
+	dk, err := GenerateKeyWithPCT()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, Ke, err := Encapsulate(dk.EncapsulationKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+	Kd, err := Decapsulate(dk, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(Ke, Kd) {
+		t.Errorf("Ke != Kd")
+	}
+
+	defer SetPCTFault(func(sharedKey []byte) { sharedKey[0] ^= 1 })()
+	dk, err = GenerateKeyWithPCT()
+	if !errors.Is(err, ErrPairwiseConsistency) {
+		t.Errorf("got %v, want %v", err, ErrPairwiseConsistency)
+	}
+	if dk != nil {
+		t.Errorf("got a key from a failed pairwise consistency test")
+	}
+}
+
+func TestSelfTest(t *testing.T) {
+	if err := SelfTest(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReader(t *testing.T) {
+	s := sha3.NewSHAKE128()
+	s.Write([]byte("mlkem768 reader test"))
+	rand := make([]byte, SeedSize+32)
+	s.Read(rand)
+
+	dk, err := GenerateKeyFromReader(bytes.NewReader(rand))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(dk.Bytes(), rand[:SeedSize]) {
+		t.Errorf("GenerateKeyFromReader did not use the reader output as seed")
+	}
+
+	c, Ke, err := EncapsulateWithReader(bytes.NewReader(rand[SeedSize:]), dk.EncapsulationKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+	c1, Ke1, err := EncapsulateDerand(dk.EncapsulationKey(), rand[SeedSize:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(c, c1) || !bytes.Equal(Ke, Ke1) {
+		t.Errorf("EncapsulateWithReader and EncapsulateDerand disagree")
+	}
+	Kd, err := Decapsulate(dk, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(Ke, Kd) {
+		t.Errorf("Ke != Kd")
+	}
+
+	if _, err := GenerateKeyFromReader(bytes.NewReader(rand[:SeedSize-1])); err == nil {
+		t.Errorf("expected error for short reader")
+	}
+	if _, _, err := EncapsulateWithReader(bytes.NewReader(rand[:31]), dk.EncapsulationKey()); err == nil {
+		t.Errorf("expected error for short reader")
+	}
+}
+
+// TestFIPS140Only runs in a child process with GODEBUG=fips140=only, since the
+// mode can't be changed at run time, and checks that derandomized encapsulation
+// and readers other than crypto/rand.Reader are refused, like in the standard
+// library.
+func TestFIPS140Only(t *testing.T) {
+	if os.Getenv("MLKEM768_TEST_FIPS140_ONLY") != "1" {
+		cmd := exec.Command(os.Args[0], "-test.run=^TestFIPS140Only$", "-test.v")
+		cmd.Env = append(os.Environ(), "MLKEM768_TEST_FIPS140_ONLY=1", "GODEBUG=fips140=only")
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("FIPS 140-only tests failed: %v\n%s", err, out)
+		}
+		return
+	}
+
+	// X25519 is not approved, so this makes sure the mode is in effect.
+	if _, err := ecdh.X25519().GenerateKey(rand.Reader); err == nil {
+		t.Fatal("FIPS 140-only mode is not enforced")
+	}
+
+	r := bytes.NewReader(make([]byte, SeedSize+32))
+	if _, err := GenerateKeyFromReader(r); err == nil {
+		t.Errorf("GenerateKeyFromReader: expected error in FIPS 140-only mode")
+	}
+	dk, err := GenerateKeyFromReader(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ek := dk.EncapsulationKey()
+	if _, _, err := EncapsulateWithReader(r, ek); err == nil {
+		t.Errorf("EncapsulateWithReader: expected error in FIPS 140-only mode")
+	}
+	if _, _, err := EncapsulateDerand(ek, make([]byte, 32)); err == nil {
+		t.Errorf("EncapsulateDerand: expected error in FIPS 140-only mode")
+	}
+
+	c, Ke, err := EncapsulateWithReader(rand.Reader, ek)
+	if err != nil {
+		t.Fatal(err)
+	}
+	Kd, err := Decapsulate(dk, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(Ke, Kd) {
+		t.Errorf("Ke != Kd")
+	}
+}
+
+func TestDestroy(t *testing.T) {
+	seed := make([]byte, SeedSize)
+	rand.Read(seed)
+	dk, err := NewKeyFromSeed(seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expanded, err := NewKeyFromExpanded(dk.ExpandedBytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, _, err := Encapsulate(dk.EncapsulationKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, dk := range map[string]*DecapsulationKey{"seed": dk, "expanded": expanded} {
+		// Check the memory the key material actually lives in, rather than
+		// the fields of dk, and make sure it's the right memory by looking
+		// for the secret z half of the seed in it first.
+		// With Go 1.26 and later, keys created from a seed are held by
+		// crypto/mlkem, and Destroy can only drop the reference to them.
+		mem := KeyMemory(dk)
+		if mem != nil && !bytes.Contains(mem, seed[32:]) {
+			t.Fatalf("%s: z not found in key memory before Destroy", name)
+		}
+		dk.Destroy()
+		if i := slices.IndexFunc(mem, func(b byte) bool { return b != 0 }); i >= 0 {
+			t.Errorf("%s: key memory is not zero after Destroy at offset %d", name, i)
+		}
+
+		if _, err := Decapsulate(dk, c); err == nil {
+			t.Errorf("%s: expected error from Decapsulate after Destroy", name)
+		}
+		var K [SharedKeySize]byte
+		if err := DecapsulateTo(&K, dk, c); err == nil {
+			t.Errorf("%s: expected error from DecapsulateTo after Destroy", name)
+		}
+		for method, f := range map[string]func(){
+			"Bytes":            func() { dk.Bytes() },
+			"EncapsulationKey": func() { dk.EncapsulationKey() },
+		} {
+			func() {
+				defer func() {
+					if recover() == nil {
+						t.Errorf("%s: expected %s to panic after Destroy", name, method)
+					}
+				}()
+				f()
+			}()
+		}
+	}
+}
+
 func TestBadLengths(t *testing.T) {
 	dk, err := GenerateKey()
 	if err != nil {
@@ -172,6 +629,189 @@ func TestAccumulated(t *testing.T) {
 	}
 }
 
+// FuzzNewKeyFromSeed checks that NewKeyFromSeed rejects seeds of the wrong
+// length, and that the keys it returns are valid and round-trip.
+func FuzzNewKeyFromSeed(f *testing.F) {
+	f.Add(make([]byte, SeedSize))
+	f.Add(make([]byte, SeedSize-1))
+	f.Add(make([]byte, SeedSize+1))
+	f.Fuzz(func(t *testing.T, seed []byte) {
+		dk, err := NewKeyFromSeed(seed)
+		if len(seed) != SeedSize {
+			if !errors.Is(err, ErrInvalidSeed) {
+				t.Fatalf("seed length %d: got %v, expected %v", len(seed), err, ErrInvalidSeed)
+			}
+			return
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(dk.Bytes(), seed) {
+			t.Errorf("Bytes: got %x, expected %x", dk.Bytes(), seed)
+		}
+		ek := dk.EncapsulationKey()
+		if err := ValidateEncapsulationKey(ek); err != nil {
+			t.Errorf("ValidateEncapsulationKey: %v", err)
+		}
+		if err := ValidateDecapsulationKey(dk.ExpandedBytes()); err != nil {
+			t.Errorf("ValidateDecapsulationKey: %v", err)
+		}
+		c, Ke, err := Encapsulate(ek)
+		if err != nil {
+			t.Fatal(err)
+		}
+		Kd, err := Decapsulate(dk, c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(Ke, Kd) {
+			t.Errorf("Decapsulate: got %x, expected %x", Kd, Ke)
+		}
+	})
+}
+
+// FuzzNewKeyFromExpanded checks that NewKeyFromExpanded agrees with
+// ValidateDecapsulationKey, and that the keys it returns can be used.
+func FuzzNewKeyFromExpanded(f *testing.F) {
+	dk, err := NewKeyFromSeed(make([]byte, SeedSize))
+	if err != nil {
+		f.Fatal(err)
+	}
+	expanded := dk.ExpandedBytes()
+	f.Add(expanded)
+	f.Add(expanded[1:])
+	badHash := bytes.Clone(expanded)
+	badHash[ExpandedDecapsulationKeySize-64] ^= 1
+	f.Add(badHash)
+	f.Fuzz(func(t *testing.T, expanded []byte) {
+		dk, err := NewKeyFromExpanded(expanded)
+		if verr := ValidateDecapsulationKey(expanded); verr != nil {
+			if !errors.Is(err, ErrInvalidDecapsulationKey) {
+				t.Fatalf("got %v, expected %v", err, verr)
+			}
+			if len(expanded) != ExpandedDecapsulationKeySize && !errors.Is(err, ErrKeyLength) {
+				t.Errorf("length %d: got %v, expected %v", len(expanded), err, ErrKeyLength)
+			}
+			return
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(dk.ExpandedBytes(), expanded) {
+			t.Errorf("ExpandedBytes: got %x, expected %x", dk.ExpandedBytes(), expanded)
+		}
+		// The decryption key is not checked against the encapsulation key, so
+		// the two might not match, and only the absence of errors is checked.
+		c, _, err := Encapsulate(dk.EncapsulationKey())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Decapsulate(dk, c); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+// FuzzEncapsulate checks that EncapsulateDerand accepts exactly the
+// encapsulation keys accepted by ValidateEncapsulationKey, and returns the
+// expected errors for the others.
+func FuzzEncapsulate(f *testing.F) {
+	dk, err := NewKeyFromSeed(make([]byte, SeedSize))
+	if err != nil {
+		f.Fatal(err)
+	}
+	ek := dk.EncapsulationKey()
+	f.Add(ek, make([]byte, 32))
+	f.Add(ek[1:], make([]byte, 32))
+	f.Add(ek, make([]byte, 31))
+	unreduced := bytes.Clone(ek)
+	unreduced[0], unreduced[1] = 0x01, 0x0d // q = 3329 = 0xd01
+	f.Add(unreduced, make([]byte, 32))
+	f.Fuzz(func(t *testing.T, ek, m []byte) {
+		c, K, err := EncapsulateDerand(ek, m)
+		switch verr := ValidateEncapsulationKey(ek); {
+		case len(m) != 32:
+			if !errors.Is(err, ErrInvalidRandomness) {
+				t.Fatalf("randomness length %d: got %v, expected %v", len(m), err, ErrInvalidRandomness)
+			}
+		case len(ek) != EncapsulationKeySize:
+			if !errors.Is(err, ErrInvalidEncapsulationKey) || !errors.Is(err, ErrKeyLength) {
+				t.Fatalf("ek length %d: got %v, expected %v", len(ek), err, ErrKeyLength)
+			}
+		case verr != nil:
+			if !errors.Is(err, ErrInvalidEncapsulationKey) || !errors.Is(err, ErrKeyModulus) {
+				t.Fatalf("got %v, expected %v", err, verr)
+			}
+		default:
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(c) != CiphertextSize || len(K) != SharedKeySize {
+				t.Fatalf("got lengths %d and %d", len(c), len(K))
+			}
+			k, err := NewEncapsulationKey(ek)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(k.Bytes(), ek) {
+				t.Errorf("Bytes: got %x, expected %x", k.Bytes(), ek)
+			}
+		}
+	})
+}
+
+// FuzzDecapsulate checks that Decapsulate agrees with encapsulation for a key
+// and message derived from the first input, and that it handles arbitrary
+// ciphertexts without panicking, returning errors only for bad lengths.
+func FuzzDecapsulate(f *testing.F) {
+	f.Add([]byte("key"), make([]byte, CiphertextSize))
+	f.Add([]byte("key"), make([]byte, CiphertextSize-1))
+	f.Add([]byte("key"), make([]byte, CiphertextSize+1))
+	f.Fuzz(func(t *testing.T, entropy, c []byte) {
+		s := sha3.NewSHAKE128()
+		s.Write(entropy)
+		seed := make([]byte, SeedSize)
+		s.Read(seed)
+		m := make([]byte, 32)
+		s.Read(m)
+
+		dk, err := NewKeyFromSeed(seed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ct, Ke, err := EncapsulateDerand(dk.EncapsulationKey(), m)
+		if err != nil {
+			t.Fatal(err)
+		}
+		Kd, err := Decapsulate(dk, ct)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(Ke, Kd) {
+			t.Errorf("Decapsulate: got %x, expected %x", Kd, Ke)
+		}
+
+		K, err := Decapsulate(dk, c)
+		var K1 [SharedKeySize]byte
+		err1 := DecapsulateTo(&K1, dk, c)
+		if len(c) != CiphertextSize {
+			if !errors.Is(err, ErrInvalidCiphertextLength) || !errors.Is(err1, ErrInvalidCiphertextLength) {
+				t.Fatalf("ciphertext length %d: got %v and %v, expected %v", len(c), err, err1, ErrInvalidCiphertextLength)
+			}
+			return
+		}
+		if err != nil || err1 != nil {
+			t.Fatalf("got %v and %v", err, err1)
+		}
+		if !bytes.Equal(K, K1[:]) {
+			t.Errorf("DecapsulateTo: got %x, expected %x", K1, K)
+		}
+		if !bytes.Equal(c, ct) && bytes.Equal(K, Ke) {
+			t.Errorf("different ciphertext decapsulated to the same shared key")
+		}
+	})
+}
+
 var sink byte
 
 func BenchmarkRoundTrip(b *testing.B) {
@@ -209,4 +849,15 @@ func BenchmarkRoundTrip(b *testing.B) {
 			sink ^= cS[0] ^ Ks[0]
 		}
 	})
+	b.Run("BobReuse", func(b *testing.B) {
+		ek, err := NewEncapsulationKey(ek)
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			cS, Ks := ek.Encapsulate()
+			sink ^= cS[0] ^ Ks[0]
+		}
+	})
 }
@@ -0,0 +1,52 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mlkem768
+
+import (
+	"crypto/subtle"
+	"fmt"
+)
+
+// GenerateKeyWithPCT works like [GenerateKey], but also performs a pairwise
+// consistency test on the generated key, as required by FIPS 140-3 for newly
+// generated key pairs. A shared key is encapsulated to the encapsulation key,
+// and is checked to match the result of decapsulating the ciphertext with the
+// decapsulation key.
+//
+// If the test fails, the key is destroyed and [ErrPairwiseConsistency] is
+// returned.
+func GenerateKeyWithPCT() (*DecapsulationKey, error) {
+	dk, err := GenerateKey()
+	if err != nil {
+		return nil, err
+	}
+	if err := pairwiseConsistencyTest(dk); err != nil {
+		dk.Destroy()
+		return nil, err
+	}
+	return dk, nil
+}
+
+// pctFault, if not nil, is called with the decapsulated shared key during the
+// pairwise consistency test. It is used by tests to inject faults.
+var pctFault func(sharedKey []byte)
+
+func pairwiseConsistencyTest(dk *DecapsulationKey) error {
+	ciphertext, sharedKey, err := Encapsulate(dk.EncapsulationKey())
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrPairwiseConsistency, err)
+	}
+	K, err := Decapsulate(dk, ciphertext)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrPairwiseConsistency, err)
+	}
+	if pctFault != nil {
+		pctFault(K)
+	}
+	if subtle.ConstantTimeCompare(sharedKey, K) != 1 {
+		return ErrPairwiseConsistency
+	}
+	return nil
+}
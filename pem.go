@@ -0,0 +1,53 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mlkem768
+
+import (
+	"fmt"
+
+	"filippo.io/mlkem768/internal/pem"
+)
+
+// EncodePrivateKeyPEM encodes dk as a "PRIVATE KEY" PEM block containing the
+// output of [MarshalPKCS8PrivateKey].
+func EncodePrivateKeyPEM(dk *DecapsulationKey) ([]byte, error) {
+	der, err := MarshalPKCS8PrivateKey(dk)
+	if err != nil {
+		return nil, err
+	}
+	return pem.Encode("PRIVATE KEY", der), nil
+}
+
+// DecodePrivateKeyPEM decodes a "PRIVATE KEY" PEM block and parses its
+// contents with [ParsePKCS8PrivateKey]. The input must contain exactly one PEM
+// block, optionally surrounded by whitespace.
+func DecodePrivateKeyPEM(data []byte) (*DecapsulationKey, error) {
+	der, err := pem.Decode(data, "PRIVATE KEY")
+	if err != nil {
+		return nil, fmt.Errorf("mlkem768: %w", err)
+	}
+	return ParsePKCS8PrivateKey(der)
+}
+
+// EncodePublicKeyPEM encodes ek as a "PUBLIC KEY" PEM block containing the
+// output of [MarshalPKIXPublicKey].
+func EncodePublicKeyPEM(ek *EncapsulationKey) ([]byte, error) {
+	der, err := MarshalPKIXPublicKey(ek)
+	if err != nil {
+		return nil, err
+	}
+	return pem.Encode("PUBLIC KEY", der), nil
+}
+
+// DecodePublicKeyPEM decodes a "PUBLIC KEY" PEM block and parses its contents
+// with [ParsePKIXPublicKey]. The input must contain exactly one PEM block,
+// optionally surrounded by whitespace.
+func DecodePublicKeyPEM(data []byte) (*EncapsulationKey, error) {
+	der, err := pem.Decode(data, "PUBLIC KEY")
+	if err != nil {
+		return nil, fmt.Errorf("mlkem768: %w", err)
+	}
+	return ParsePKIXPublicKey(der)
+}
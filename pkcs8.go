@@ -0,0 +1,186 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mlkem768
+
+import (
+	"crypto/subtle"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+)
+
+// The encodings in this file are specified in [draft-ietf-lamps-kyber-certificates].
+//
+// [draft-ietf-lamps-kyber-certificates]: https://datatracker.ietf.org/doc/draft-ietf-lamps-kyber-certificates/
+
+// oidMLKEM768 is id-alg-ml-kem-768, from the NIST Computer Security Objects
+// Register. The parameters of its AlgorithmIdentifier must be absent.
+var oidMLKEM768 = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 4, 2}
+
+// pkcs8 is the OneAsymmetricKey structure of RFC 5958, which is compatible
+// with the PrivateKeyInfo structure of PKCS #8 (RFC 5208).
+type pkcs8 struct {
+	Version    int
+	Algo       pkix.AlgorithmIdentifier
+	PrivateKey []byte
+	Attributes asn1.RawValue  `asn1:"optional,tag:0"`
+	PublicKey  asn1.BitString `asn1:"optional,tag:1"`
+}
+
+// spki is the SubjectPublicKeyInfo structure of RFC 5280.
+type spki struct {
+	Algorithm pkix.AlgorithmIdentifier
+	PublicKey asn1.BitString
+}
+
+// privateKeyBoth is the "both" alternative of the ML-KEM-768-PrivateKey CHOICE.
+type privateKeyBoth struct {
+	Seed        []byte
+	ExpandedKey []byte
+}
+
+// MarshalPKCS8PrivateKey encodes dk as a PKCS #8 (RFC 5208 and RFC 5958)
+// private key, with the id-alg-ml-kem-768 algorithm identifier.
+//
+// The private key is encoded in the recommended 64-byte seed form, unless dk
+// was created by [NewKeyFromExpanded], in which case the seed is not known and
+// the 2400-byte expandedKey form is used instead.
+func MarshalPKCS8PrivateKey(dk *DecapsulationKey) ([]byte, error) {
+	if dk.destroyed {
+		return nil, ErrKeyDestroyed
+	}
+	var privateKey []byte
+	var err error
+	if dk.hasSeed() {
+		// seed [0] IMPLICIT OCTET STRING (SIZE (64))
+		privateKey, err = asn1.Marshal(asn1.RawValue{
+			Class: asn1.ClassContextSpecific, Tag: 0, Bytes: dk.Bytes(),
+		})
+	} else {
+		// expandedKey OCTET STRING (SIZE (2400))
+		privateKey, err = asn1.Marshal(dk.ExpandedBytes())
+	}
+	if err != nil {
+		return nil, err
+	}
+	return asn1.Marshal(pkcs8{
+		Algo:       pkix.AlgorithmIdentifier{Algorithm: oidMLKEM768},
+		PrivateKey: privateKey,
+	})
+}
+
+// ParsePKCS8PrivateKey parses a PKCS #8 (RFC 5208 and RFC 5958) private key
+// with the id-alg-ml-kem-768 algorithm identifier.
+//
+// All three forms of the private key are supported: seed, expandedKey, and
+// both. If both are present, the expanded key must match the one derived from
+// the seed. If the optional public key is present, it must match as well, and
+// the version must be v2 as required by RFC 5958. Otherwise, it must be v1.
+func ParsePKCS8PrivateKey(der []byte) (*DecapsulationKey, error) {
+	var p pkcs8
+	if rest, err := asn1.Unmarshal(der, &p); err != nil {
+		return nil, fmt.Errorf("mlkem768: invalid PKCS #8 private key: %w", err)
+	} else if len(rest) != 0 {
+		return nil, errors.New("mlkem768: trailing data after PKCS #8 private key")
+	}
+	// RFC 5958 requires version v2 (1) if and only if the public key is present.
+	// An empty public key still has a non-nil Bytes field.
+	hasPublicKey := p.PublicKey.Bytes != nil
+	switch {
+	case p.Version != 0 && p.Version != 1:
+		return nil, fmt.Errorf("mlkem768: unsupported PKCS #8 version %d", p.Version)
+	case hasPublicKey && p.Version != 1:
+		return nil, errors.New("mlkem768: PKCS #8 private key with a public key is not version v2")
+	case !hasPublicKey && p.Version != 0:
+		return nil, errors.New("mlkem768: PKCS #8 private key without a public key is not version v1")
+	}
+	if err := checkAlgorithm(p.Algo); err != nil {
+		return nil, err
+	}
+
+	var raw asn1.RawValue
+	if rest, err := asn1.Unmarshal(p.PrivateKey, &raw); err != nil {
+		return nil, fmt.Errorf("mlkem768: invalid ML-KEM-768 private key: %w", err)
+	} else if len(rest) != 0 {
+		return nil, errors.New("mlkem768: trailing data after ML-KEM-768 private key")
+	}
+	var dk *DecapsulationKey
+	var err error
+	switch {
+	case raw.Class == asn1.ClassContextSpecific && raw.Tag == 0 && !raw.IsCompound:
+		dk, err = NewKeyFromSeed(raw.Bytes)
+	case raw.Class == asn1.ClassUniversal && raw.Tag == asn1.TagOctetString && !raw.IsCompound:
+		dk, err = NewKeyFromExpanded(raw.Bytes)
+	case raw.Class == asn1.ClassUniversal && raw.Tag == asn1.TagSequence && raw.IsCompound:
+		var both privateKeyBoth
+		if rest, err := asn1.Unmarshal(raw.FullBytes, &both); err != nil {
+			return nil, fmt.Errorf("mlkem768: invalid ML-KEM-768 private key: %w", err)
+		} else if len(rest) != 0 {
+			return nil, errors.New("mlkem768: trailing data after ML-KEM-768 private key")
+		}
+		if len(both.ExpandedKey) != ExpandedDecapsulationKeySize {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidDecapsulationKey, ErrKeyLength)
+		}
+		dk, err = NewKeyFromSeed(both.Seed)
+		if err == nil && subtle.ConstantTimeCompare(dk.ExpandedBytes(), both.ExpandedKey) != 1 {
+			dk.Destroy()
+			return nil, fmt.Errorf("%w: seed and expanded key don't match", ErrInvalidDecapsulationKey)
+		}
+	default:
+		return nil, errors.New("mlkem768: unknown ML-KEM-768 private key form")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if hasPublicKey {
+		if p.PublicKey.BitLength != 8*len(p.PublicKey.Bytes) ||
+			subtle.ConstantTimeCompare(p.PublicKey.Bytes, dk.EncapsulationKey()) != 1 {
+			dk.Destroy()
+			return nil, errors.New("mlkem768: PKCS #8 public key doesn't match the private key")
+		}
+	}
+	return dk, nil
+}
+
+// MarshalPKIXPublicKey encodes ek as a SubjectPublicKeyInfo (RFC 5280) with
+// the id-alg-ml-kem-768 algorithm identifier.
+func MarshalPKIXPublicKey(ek *EncapsulationKey) ([]byte, error) {
+	b := ek.Bytes()
+	return asn1.Marshal(spki{
+		Algorithm: pkix.AlgorithmIdentifier{Algorithm: oidMLKEM768},
+		PublicKey: asn1.BitString{Bytes: b, BitLength: 8 * len(b)},
+	})
+}
+
+// ParsePKIXPublicKey parses a SubjectPublicKeyInfo (RFC 5280) with the
+// id-alg-ml-kem-768 algorithm identifier. The encapsulation key is checked
+// like by [NewEncapsulationKey].
+func ParsePKIXPublicKey(der []byte) (*EncapsulationKey, error) {
+	var p spki
+	if rest, err := asn1.Unmarshal(der, &p); err != nil {
+		return nil, fmt.Errorf("mlkem768: invalid SubjectPublicKeyInfo: %w", err)
+	} else if len(rest) != 0 {
+		return nil, errors.New("mlkem768: trailing data after SubjectPublicKeyInfo")
+	}
+	if err := checkAlgorithm(p.Algorithm); err != nil {
+		return nil, err
+	}
+	if p.PublicKey.BitLength != 8*len(p.PublicKey.Bytes) {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidEncapsulationKey, ErrKeyLength)
+	}
+	return NewEncapsulationKey(p.PublicKey.Bytes)
+}
+
+func checkAlgorithm(algo pkix.AlgorithmIdentifier) error {
+	if !algo.Algorithm.Equal(oidMLKEM768) {
+		return fmt.Errorf("mlkem768: unsupported algorithm %v", algo.Algorithm)
+	}
+	if len(algo.Parameters.FullBytes) != 0 {
+		return errors.New("mlkem768: unexpected id-alg-ml-kem-768 parameters")
+	}
+	return nil
+}
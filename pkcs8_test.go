@@ -0,0 +1,192 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mlkem768_test
+
+import (
+	"bytes"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	. "filippo.io/mlkem768"
+)
+
+var oidMLKEM768 = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 4, 2}
+
+func testSeed() []byte {
+	seed := make([]byte, SeedSize)
+	for i := range seed {
+		seed[i] = byte(i)
+	}
+	return seed
+}
+
+func TestPKCS8(t *testing.T) {
+	seed := testSeed()
+	dk, err := NewKeyFromSeed(seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A version 0 OneAsymmetricKey with the private key in the seed form, that
+	// is [0] IMPLICIT OCTET STRING.
+	want, _ := hex.DecodeString("3054020100300b060960864801650304040204428040" + hex.EncodeToString(seed))
+	der, err := MarshalPKCS8PrivateKey(dk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(der, want) {
+		t.Errorf("MarshalPKCS8PrivateKey: got %x, expected %x", der, want)
+	}
+	dk1, err := ParsePKCS8PrivateKey(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(dk1.Bytes(), seed) {
+		t.Errorf("ParsePKCS8PrivateKey: got seed %x, expected %x", dk1.Bytes(), seed)
+	}
+
+	expanded := dk.ExpandedBytes()
+	dkx, err := NewKeyFromExpanded(expanded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err = MarshalPKCS8PrivateKey(dkx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dk1, err = ParsePKCS8PrivateKey(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(dk1.ExpandedBytes(), expanded) {
+		t.Errorf("ParsePKCS8PrivateKey: expanded key doesn't round-trip")
+	}
+
+	marshal := func(version int, params asn1.RawValue, privateKey any, publicKey []byte) []byte {
+		t.Helper()
+		pk, err := asn1.Marshal(privateKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		der, err := asn1.Marshal(struct {
+			Version    int
+			Algo       pkix.AlgorithmIdentifier
+			PrivateKey []byte
+			PublicKey  asn1.BitString `asn1:"optional,tag:1"`
+		}{version, pkix.AlgorithmIdentifier{Algorithm: oidMLKEM768, Parameters: params},
+			pk, asn1.BitString{Bytes: publicKey, BitLength: 8 * len(publicKey)}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return der
+	}
+	seedForm := asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, Bytes: seed}
+	both := struct{ Seed, ExpandedKey []byte }{seed, expanded}
+	badExpanded := bytes.Clone(expanded)
+	badExpanded[ExpandedDecapsulationKeySize-64] ^= 1 // H(ek)
+	badBoth := struct{ Seed, ExpandedKey []byte }{seed, badExpanded}
+	badEK := bytes.Clone(dk.EncapsulationKey())
+	badEK[0] ^= 1
+
+	for _, tt := range []struct {
+		name  string
+		der   []byte
+		valid bool
+	}{
+		{"seed", marshal(0, asn1.RawValue{}, seedForm, nil), true},
+		{"expandedKey", marshal(0, asn1.RawValue{}, expanded, nil), true},
+		{"both", marshal(0, asn1.RawValue{}, both, nil), true},
+		{"inconsistent both", marshal(0, asn1.RawValue{}, badBoth, nil), false},
+		{"public key", marshal(1, asn1.RawValue{}, seedForm, dk.EncapsulationKey()), true},
+		{"wrong public key", marshal(1, asn1.RawValue{}, seedForm, badEK), false},
+		{"short seed", marshal(0, asn1.RawValue{}, asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, Bytes: seed[1:]}, nil), false},
+		{"short expandedKey", marshal(0, asn1.RawValue{}, expanded[1:], nil), false},
+		{"invalid expandedKey", marshal(0, asn1.RawValue{}, badExpanded, nil), false},
+		{"unknown form", marshal(0, asn1.RawValue{}, 42, nil), false},
+		{"NULL parameters", marshal(0, asn1.NullRawValue, seedForm, nil), false},
+		{"version 2", marshal(2, asn1.RawValue{}, seedForm, nil), false},
+		{"v2 without public key", marshal(1, asn1.RawValue{}, seedForm, nil), false},
+		{"v1 with public key", marshal(0, asn1.RawValue{}, seedForm, dk.EncapsulationKey()), false},
+		{"empty public key", marshal(1, asn1.RawValue{}, seedForm, []byte{}), false},
+		{"trailing data", append(marshal(0, asn1.RawValue{}, seedForm, nil), 0), false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			dk, err := ParsePKCS8PrivateKey(tt.der)
+			if !tt.valid {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(dk.ExpandedBytes(), expanded) {
+				t.Errorf("got a different key")
+			}
+		})
+	}
+
+	_, err = ParsePKCS8PrivateKey(marshal(0, asn1.RawValue{}, badBoth, nil))
+	if !errors.Is(err, ErrInvalidDecapsulationKey) {
+		t.Errorf("inconsistent both: got %v, expected %v", err, ErrInvalidDecapsulationKey)
+	}
+
+	dk.Destroy()
+	if _, err := MarshalPKCS8PrivateKey(dk); !errors.Is(err, ErrKeyDestroyed) {
+		t.Errorf("MarshalPKCS8PrivateKey: got %v, expected %v", err, ErrKeyDestroyed)
+	}
+}
+
+func TestPKIX(t *testing.T) {
+	dk, err := NewKeyFromSeed(testSeed())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ek, err := NewEncapsulationKey(dk.EncapsulationKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want, _ := hex.DecodeString("308204b2300b0609608648016503040402038204a100" + hex.EncodeToString(ek.Bytes()))
+	der, err := MarshalPKIXPublicKey(ek)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(der, want) {
+		t.Errorf("MarshalPKIXPublicKey: got %x, expected %x", der, want)
+	}
+	ek1, err := ParsePKIXPublicKey(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(ek1.Bytes(), ek.Bytes()) {
+		t.Errorf("ParsePKIXPublicKey: got %x, expected %x", ek1.Bytes(), ek.Bytes())
+	}
+
+	unreduced := bytes.Clone(der)
+	unreduced[len(want)-EncapsulationKeySize] = 0xff
+	unreduced[len(want)-EncapsulationKeySize+1] = 0xff
+	if _, err := ParsePKIXPublicKey(unreduced); !errors.Is(err, ErrInvalidEncapsulationKey) {
+		t.Errorf("unreduced key: got %v, expected %v", err, ErrInvalidEncapsulationKey)
+	}
+	short := bytes.Clone(der[:len(der)-1])
+	short[2], short[3] = 0x04, 0xb1
+	short[19], short[20] = 0x04, 0xa0
+	if _, err := ParsePKIXPublicKey(short); !errors.Is(err, ErrKeyLength) {
+		t.Errorf("short key: got %v, expected %v", err, ErrKeyLength)
+	}
+	wrongOID := bytes.Clone(der)
+	wrongOID[16] = 0x03 // id-alg-ml-kem-1024
+	if _, err := ParsePKIXPublicKey(wrongOID); err == nil {
+		t.Errorf("wrong OID: expected error")
+	}
+	if _, err := ParsePKIXPublicKey(append(der, 0)); err == nil {
+		t.Errorf("trailing data: expected error")
+	}
+}
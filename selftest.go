@@ -0,0 +1,110 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mlkem768
+
+import (
+	"crypto/sha3"
+	"encoding/hex"
+	"fmt"
+)
+
+// The known-answer test uses the key generated from the seed 00 01 02 ... 3f.
+// EncapsulateDerand with the randomness 40 41 42 ... 5f produces
+// selfTestCiphertext and selfTestSharedKey, and with its first byte inverted,
+// the ciphertext decapsulates to the implicit rejection key selfTestRejectedKey.
+const (
+	selfTestEncapsulationKeyHash = "a24e16d8f8f9383a95b77050f4d9fd2f5733eec1d63ef3c23ebf9918173669a7"
+	selfTestSharedKey            = "9cddd089ffe70e3996e76f7c8d06746df34d07e8657bc0fcf2bb0e1c3084aea1"
+	selfTestRejectedKey          = "c5aeb2bc62a615d9050013d9508d5321d760197e54fe2a6ff17c6cfd101c3389"
+
+	selfTestCiphertext = "" +
+		"695a60d9c79f08343ed9ff5802582063c2ca3a648e543d924affbb39ef4de656" +
+		"591f0d7689e6626be7ea7fedaf134e2c27c6797c73a5edaf16808f141c8afcf3" +
+		"1614e8ab665379573e4d0a2037cbf776048167ba53576001a2596402cf24b5d4" +
+		"5362bc893ceaef3599f76b10812e626002e66db5c5b0f2b9a7080e32db68dcc8" +
+		"d04c24f8461a58bb7e47efe670d740ad8af9820033845ef5f880f26f0e00adb2" +
+		"abef876f5270477ebbb02de6787ce72ca8785fb181f46c3ff7ae3787c25c68cc" +
+		"ceefb3551875b9d77c4d439b6050eb382aacf9e744227e8c46e0a9a55838ea70" +
+		"34f5b4bcb61f1023a80186e795f4b3d8ae93988994224fa2d83e21711670da01" +
+		"e2b3e272f81616c0bc88cc46f641d16e0d0c0924cf4a4a5c1a9128c226d4918a" +
+		"a39bef94199dfffa33876ef0bfa0d9560d25f5ba08068d5271f32d2f9d88bcf5" +
+		"3c7dcf811a8d5efe617f5e05700d3478d3cb7932528d1bceb240198a4cf8752c" +
+		"aea3d387f00759a1356b7a5bf1838d26c3573e92e69f0f57c06e8c25459eb83e" +
+		"12cdd75f541a81ce710eafce2984783f30e37b327ff93b72297c6cd8c78c185a" +
+		"d53864952069d7d6c3bc633ae5e1a5925855df0b7e714bbde245f68822e0950c" +
+		"23c96d6111753a6ed0c46cce437f53b6bb708c1a3e25979733198d9879e3237e" +
+		"769471f922e579f37cfd641d29bdcfdbaa81edae09aeb046366e0376d04282d1" +
+		"7778a8d54774e8c9be3c822b1e90cd8895abc1db8951b7687f63fee50ec43faf" +
+		"23730b15189e7c982b22d896a972da3c2ee529bb5fe63630c9c2ddfb9d1e4263" +
+		"a3d49af2832053d97efa2bd1782f25d7b864d6fb3708bfb9d4bc6c2cc6458d4f" +
+		"1459995db387e8b503825a4496c735252aa630a1bcaa7a2674727396dcaf6703" +
+		"0b53473951651dc26c22476bfd11d33206af0ff035ed035e34716c905e8ddf04" +
+		"3a4cdae145238d8f612dbcb75e879653bb9e2657dab58b944ff34f977fe15ce9" +
+		"07f6814a5f92338774e6f2ab5257d24917decdd158c6d4594189f42a9b7fa915" +
+		"9a8af6aa825ba904654e08c894901298ffb27239ddea8283dd45b876036c0aec" +
+		"f03583ba444529757444c857fff6e4f8ed48f8a180adea54979a678f16dc6ac8" +
+		"edcc8e72ed08e96082f0ff4520dc635d4a846a3026fd86a48b1297e0cdfc0600" +
+		"8793e783bde1c3fc6a71871e66b1feb560495817aabbdc59f0149f3e76add9b5" +
+		"bd6ce34734de7593ed607efb84c6e732960c744c908a9cb8947375a55b55fa2f" +
+		"0cd6742b75c10f65522d3844bed9b05bd441bbbea17cfbabdaef9847a0edd9c8" +
+		"329a762e34e5396014d88b4d344f250aaddefd917bb2120d1169c79cb09f59ba" +
+		"d21850752c1099fff98b71bcdaab76f7063323e78faa521cd243f74ddc7f7775" +
+		"aa79960622e13580a6831e69bb7f2321d141d35da88317719078d4db319f3085" +
+		"94c26836503f62362c40005022937c1298a928c040879661349a7b5362d0a75f" +
+		"2893b97a2600d5337239a70a6b64a457e6dfd5c74d462e7e790bb9ef3cee1461"
+)
+
+// SelfTest runs a known-answer test of key generation, encapsulation, and
+// decapsulation, including implicit rejection, and returns [ErrSelfTest] if
+// any result doesn't match the expected value.
+//
+// SelfTest is meant to be called at startup by applications that need to check
+// the implementation before using it. It takes about as long as a few
+// encapsulations.
+func SelfTest() error {
+	seed := make([]byte, SeedSize)
+	for i := range seed {
+		seed[i] = byte(i)
+	}
+	m := make([]byte, 32)
+	for i := range m {
+		m[i] = byte(SeedSize + i)
+	}
+
+	dk, err := NewKeyFromSeed(seed)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrSelfTest, err)
+	}
+	defer dk.Destroy()
+	ek := dk.EncapsulationKey()
+	if h := sha3.Sum256(ek); hex.EncodeToString(h[:]) != selfTestEncapsulationKeyHash {
+		return fmt.Errorf("%w: key generation known answer mismatch", ErrSelfTest)
+	}
+
+	c, K, err := EncapsulateDerand(ek, m)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrSelfTest, err)
+	}
+	if hex.EncodeToString(c) != selfTestCiphertext || hex.EncodeToString(K) != selfTestSharedKey {
+		return fmt.Errorf("%w: encapsulation known answer mismatch", ErrSelfTest)
+	}
+
+	K, err = Decapsulate(dk, c)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrSelfTest, err)
+	}
+	if hex.EncodeToString(K) != selfTestSharedKey {
+		return fmt.Errorf("%w: decapsulation known answer mismatch", ErrSelfTest)
+	}
+	c[0] ^= 0xff
+	K, err = Decapsulate(dk, c)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrSelfTest, err)
+	}
+	if hex.EncodeToString(K) != selfTestRejectedKey {
+		return fmt.Errorf("%w: implicit rejection known answer mismatch", ErrSelfTest)
+	}
+	return nil
+}
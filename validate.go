@@ -0,0 +1,39 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mlkem768
+
+import "filippo.io/mlkem768/internal/mlkem"
+
+// ValidateEncapsulationKey performs the encapsulation key checks of FIPS 203,
+// Section 7.2, without parsing the key. If the key is invalid, it returns an
+// error matching [ErrInvalidEncapsulationKey] and either [ErrKeyLength] or
+// [ErrKeyModulus].
+//
+// Encapsulate and NewEncapsulationKey perform the same checks.
+func ValidateEncapsulationKey(encapsulationKey []byte) error {
+	if err := mlkem.CheckEncapsulationKey(mlkem.MLKEM768, encapsulationKey); err != nil {
+		return encapsulationKeyError(encapsulationKey, err)
+	}
+	return nil
+}
+
+// ValidateDecapsulationKey performs the decapsulation key checks of FIPS 203,
+// Section 7.3, on a key in the 2400-byte expanded form. If the key is invalid,
+// it returns an error matching [ErrInvalidDecapsulationKey] and one of
+// [ErrKeyLength], [ErrKeyModulus], or [ErrKeyHash].
+//
+// This is stricter than FIPS 203, which only requires checking the length and
+// H(ek). The embedded encapsulation key is also checked as in Section 7.2, and
+// the coefficients of dk_PKE must be reduced modulo q, or the error matches
+// [ErrKeyModulus]. Such keys can't be produced by ML-KEM.KeyGen.
+//
+// NewKeyFromExpanded performs the same checks. Keys in the 64-byte seed form
+// don't need validation, as any seed produces a valid key.
+func ValidateDecapsulationKey(expanded []byte) error {
+	if err := mlkem.CheckExpandedDecapsulationKey(mlkem.MLKEM768, expanded); err != nil {
+		return decapsulationKeyError(expanded, err)
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package xwing
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+
+	"filippo.io/mlkem768/internal/cose"
+)
+
+// COSEAlgorithm is the COSE algorithm identifier used for X-Wing keys and
+// COSE_Encrypt recipients.
+//
+// No value has been registered or proposed for X-Wing as a COSE_Encrypt
+// recipient algorithm yet, so this is a value from the private use range of the
+// COSE Algorithms registry (less than -65536). Other implementations won't
+// recognize it, and it will change once a value is assigned.
+const COSEAlgorithm = -65538
+
+// MarshalCOSEPrivateKey encodes dk as a COSE_Key (RFC 9052) of type AKP, with
+// the "priv" parameter set to the seed and the "pub" parameter set to the
+// encapsulation key.
+func MarshalCOSEPrivateKey(dk *DecapsulationKey) ([]byte, error) {
+	if dk.destroyed {
+		return nil, ErrKeyDestroyed
+	}
+	return cose.MarshalKey(COSEAlgorithm, dk.EncapsulationKey(), dk.Bytes()), nil
+}
+
+// ParseCOSEPrivateKey parses a COSE_Key produced by [MarshalCOSEPrivateKey].
+// The "pub" parameter must match the key derived from the "priv" parameter.
+func ParseCOSEPrivateKey(data []byte) (*DecapsulationKey, error) {
+	pub, priv, err := cose.ParseKey(data, COSEAlgorithm)
+	if err != nil {
+		return nil, fmt.Errorf("xwing: %w", err)
+	}
+	if priv == nil {
+		return nil, errors.New("xwing: COSE_Key has no private key")
+	}
+	dk, err := NewKeyFromSeed(priv)
+	if err != nil {
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare(pub, dk.EncapsulationKey()) != 1 {
+		dk.Destroy()
+		return nil, errors.New("xwing: COSE_Key public key doesn't match the private key")
+	}
+	return dk, nil
+}
+
+// MarshalCOSEPublicKey encodes ek as a COSE_Key (RFC 9052) of type AKP.
+func MarshalCOSEPublicKey(ek *EncapsulationKey) ([]byte, error) {
+	return cose.MarshalKey(COSEAlgorithm, ek.Bytes(), nil), nil
+}
+
+// ParseCOSEPublicKey parses a COSE_Key produced by [MarshalCOSEPublicKey].
+// To avoid mishandling secrets, it rejects keys with a "priv" parameter.
+func ParseCOSEPublicKey(data []byte) (*EncapsulationKey, error) {
+	pub, priv, err := cose.ParseKey(data, COSEAlgorithm)
+	if err != nil {
+		return nil, fmt.Errorf("xwing: %w", err)
+	}
+	if priv != nil {
+		return nil, errors.New("xwing: COSE_Key unexpectedly has a private key")
+	}
+	return NewEncapsulationKey(pub)
+}
+
+// SealCOSEEncrypt encrypts plaintext to ek, and returns a tagged COSE_Encrypt
+// (RFC 9052) message with a single recipient. kid, if not nil, is included in
+// the recipient as a hint for the receiver. externalAAD is authenticated but
+// not included in the message, and must be passed to [OpenCOSEEncrypt].
+//
+// The content is encrypted with AES-256-GCM under a random content key, which
+// is wrapped with AES-256-GCM under a key derived with HKDF-SHA256 from the
+// shared key of a fresh X-Wing encapsulation. The X-Wing ciphertext is carried
+// in the recipient's "ek" (-4) header parameter.
+//
+// This is a private format, not COSE-HPKE: only the "ek" header parameter is
+// borrowed from it, and the message can only be opened by [OpenCOSEEncrypt].
+func SealCOSEEncrypt(ek *EncapsulationKey, kid, plaintext, externalAAD []byte) ([]byte, error) {
+	msg, err := cose.Seal(COSEAlgorithm, kid, ek.Encapsulate, plaintext, externalAAD)
+	if err != nil {
+		return nil, fmt.Errorf("xwing: %w", err)
+	}
+	return msg, nil
+}
+
+// OpenCOSEEncrypt decrypts a COSE_Encrypt message produced by
+// [SealCOSEEncrypt] for the encapsulation key of dk.
+func OpenCOSEEncrypt(dk *DecapsulationKey, message, externalAAD []byte) ([]byte, error) {
+	if dk.destroyed {
+		return nil, ErrKeyDestroyed
+	}
+	decapsulate := func(ciphertext []byte) ([]byte, error) {
+		return Decapsulate(dk, ciphertext)
+	}
+	plaintext, err := cose.Open(COSEAlgorithm, decapsulate, message, externalAAD)
+	if err != nil {
+		return nil, fmt.Errorf("xwing: %w", err)
+	}
+	return plaintext, nil
+}
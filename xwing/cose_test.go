@@ -0,0 +1,209 @@
+package xwing
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"filippo.io/mlkem768"
+)
+
+func TestCOSE(t *testing.T) {
+	seed := make([]byte, SeedSize)
+	dk, err := NewKeyFromSeed(seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ek, err := NewEncapsulationKey(dk.EncapsulationKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// {1: 7, 3: -65538, -1: pub, -2: priv}
+	want := []byte{0xa4, 0x01, 0x07, 0x03, 0x3a, 0x00, 0x01, 0x00, 0x01, 0x20, 0x59, 0x04, 0xc0}
+	want = append(want, ek.Bytes()...)
+	want = append(want, 0x21, 0x58, 0x20)
+	want = append(want, seed...)
+	priv, err := MarshalCOSEPrivateKey(dk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(priv, want) {
+		t.Errorf("MarshalCOSEPrivateKey: got %x, expected %x", priv, want)
+	}
+	dk1, err := ParseCOSEPrivateKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(dk1.Bytes(), seed) {
+		t.Errorf("ParseCOSEPrivateKey: got %x, expected %x", dk1.Bytes(), seed)
+	}
+
+	pub, err := MarshalCOSEPublicKey(ek)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wantPub := append([]byte{0xa3}, want[1:len(want)-3-SeedSize]...); !bytes.Equal(pub, wantPub) {
+		t.Errorf("MarshalCOSEPublicKey: got %x, expected %x", pub, wantPub)
+	}
+	ek1, err := ParseCOSEPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(ek1.Bytes(), ek.Bytes()) {
+		t.Errorf("ParseCOSEPublicKey: got %x, expected %x", ek1.Bytes(), ek.Bytes())
+	}
+	if _, err := ParseCOSEPublicKey(priv); err == nil {
+		t.Errorf("ParseCOSEPublicKey: expected error for private key")
+	}
+	if _, err := ParseCOSEPrivateKey(pub); err == nil {
+		t.Errorf("ParseCOSEPrivateKey: expected error for public key")
+	}
+	mlkemKey := bytes.Replace(priv, []byte{0x3a, 0x00, 0x01, 0x00, 0x01}, []byte{0x3a, 0x00, 0x01, 0x00, 0x00}, 1)
+	if _, err := ParseCOSEPrivateKey(mlkemKey); err == nil {
+		t.Errorf("ParseCOSEPrivateKey: expected error for ML-KEM-768 key")
+	}
+	mismatch := bytes.Clone(priv)
+	mismatch[len(mismatch)-1] ^= 1
+	if _, err := ParseCOSEPrivateKey(mismatch); err == nil {
+		t.Errorf("ParseCOSEPrivateKey: expected error for mismatched public key")
+	}
+
+	plaintext := []byte("the quick brown fox")
+	msg, err := SealCOSEEncrypt(ek, []byte("key-1"), plaintext, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := OpenCOSEEncrypt(dk, msg, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, plaintext) {
+		t.Errorf("OpenCOSEEncrypt: got %q, expected %q", got, plaintext)
+	}
+	if _, err := OpenCOSEEncrypt(dk, msg, []byte("external")); err == nil {
+		t.Errorf("OpenCOSEEncrypt: expected error for wrong external AAD")
+	}
+	other, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := OpenCOSEEncrypt(other, msg, nil); err == nil {
+		t.Errorf("OpenCOSEEncrypt: expected error for wrong key")
+	}
+	tampered := bytes.Clone(msg)
+	tampered[len(tampered)-1] ^= 1
+	if _, err := OpenCOSEEncrypt(dk, tampered, nil); err == nil {
+		t.Errorf("OpenCOSEEncrypt: expected error for tampered message")
+	}
+
+	// An ML-KEM-768 recipient is not mistaken for an X-Wing one.
+	mk, err := mlkem768.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mek, err := mlkem768.NewEncapsulationKey(mk.EncapsulationKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+	mlkemMsg, err := mlkem768.SealCOSEEncrypt(mek, nil, plaintext, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := OpenCOSEEncrypt(dk, mlkemMsg, nil); err == nil {
+		t.Errorf("OpenCOSEEncrypt: expected error for ML-KEM-768 recipient")
+	}
+
+	dk.Destroy()
+	if _, err := MarshalCOSEPrivateKey(dk); !errors.Is(err, ErrKeyDestroyed) {
+		t.Errorf("MarshalCOSEPrivateKey: got %v, expected %v", err, ErrKeyDestroyed)
+	}
+}
+
+// FuzzParseCOSEKey checks that ParseCOSEPrivateKey and ParseCOSEPublicKey
+// don't panic, never both accept the same input, and that the keys they
+// return marshal to an equivalent COSE_Key.
+func FuzzParseCOSEKey(f *testing.F) {
+	dk, err := NewKeyFromSeed(make([]byte, SeedSize))
+	if err != nil {
+		f.Fatal(err)
+	}
+	ek, err := NewEncapsulationKey(dk.EncapsulationKey())
+	if err != nil {
+		f.Fatal(err)
+	}
+	priv, err := MarshalCOSEPrivateKey(dk)
+	if err != nil {
+		f.Fatal(err)
+	}
+	pub, err := MarshalCOSEPublicKey(ek)
+	if err != nil {
+		f.Fatal(err)
+	}
+	f.Add(priv)
+	f.Add(pub)
+	f.Add(priv[:len(priv)-1])
+	f.Add(pub[:20])
+	f.Fuzz(func(t *testing.T, data []byte) {
+		dk, privErr := ParseCOSEPrivateKey(data)
+		ek, pubErr := ParseCOSEPublicKey(data)
+		if privErr == nil && pubErr == nil {
+			t.Fatal("input parsed as both a private and a public key")
+		}
+		if privErr == nil {
+			out, err := MarshalCOSEPrivateKey(dk)
+			if err != nil {
+				t.Fatal(err)
+			}
+			dk1, err := ParseCOSEPrivateKey(out)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(dk1.Bytes(), dk.Bytes()) {
+				t.Errorf("round trip: got %x, expected %x", dk1.Bytes(), dk.Bytes())
+			}
+		}
+		if pubErr == nil {
+			out, err := MarshalCOSEPublicKey(ek)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ek1, err := ParseCOSEPublicKey(out)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(ek1.Bytes(), ek.Bytes()) {
+				t.Errorf("round trip: got %x, expected %x", ek1.Bytes(), ek.Bytes())
+			}
+		}
+	})
+}
+
+// FuzzOpenCOSEEncrypt checks that OpenCOSEEncrypt doesn't panic on arbitrary
+// messages, and that any message it accepts decrypts to the only plaintext
+// that was ever encrypted to the key.
+func FuzzOpenCOSEEncrypt(f *testing.F) {
+	dk, err := NewKeyFromSeed(make([]byte, SeedSize))
+	if err != nil {
+		f.Fatal(err)
+	}
+	ek, err := NewEncapsulationKey(dk.EncapsulationKey())
+	if err != nil {
+		f.Fatal(err)
+	}
+	plaintext, aad := []byte("the quick brown fox"), []byte("external")
+	for _, kid := range [][]byte{nil, []byte("key-1")} {
+		msg, err := SealCOSEEncrypt(ek, kid, plaintext, aad)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(msg)
+		f.Add(msg[:len(msg)-1])
+	}
+	f.Fuzz(func(t *testing.T, msg []byte) {
+		got, err := OpenCOSEEncrypt(dk, msg, aad)
+		if err == nil && !bytes.Equal(got, plaintext) {
+			t.Errorf("got plaintext %q, expected %q", got, plaintext)
+		}
+	})
+}
@@ -0,0 +1,136 @@
+package xwing
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+var (
+	_ encoding.BinaryAppender    = (*DecapsulationKey)(nil)
+	_ encoding.BinaryMarshaler   = (*DecapsulationKey)(nil)
+	_ encoding.BinaryUnmarshaler = (*DecapsulationKey)(nil)
+	_ encoding.TextMarshaler     = (*DecapsulationKey)(nil)
+	_ encoding.TextUnmarshaler   = (*DecapsulationKey)(nil)
+	_ fmt.Formatter              = (*DecapsulationKey)(nil)
+	_ fmt.Stringer               = (*DecapsulationKey)(nil)
+	_ fmt.GoStringer             = (*DecapsulationKey)(nil)
+	_ encoding.BinaryAppender    = (*EncapsulationKey)(nil)
+	_ encoding.BinaryMarshaler   = (*EncapsulationKey)(nil)
+	_ encoding.BinaryUnmarshaler = (*EncapsulationKey)(nil)
+	_ encoding.TextMarshaler     = (*EncapsulationKey)(nil)
+	_ encoding.TextUnmarshaler   = (*EncapsulationKey)(nil)
+)
+
+func TestEncoding(t *testing.T) {
+	seed := make([]byte, SeedSize)
+	seed[0] = 0xaa
+	dk, err := NewKeyFromSeed(seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ek, err := NewEncapsulationKey(dk.EncapsulationKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := dk.AppendBinary([]byte("prefix"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := append([]byte("prefix"), seed...); !bytes.Equal(b, want) {
+		t.Errorf("AppendBinary: got %x, expected %x", b, want)
+	}
+	b, err = ek.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b, ek.Bytes()) {
+		t.Errorf("MarshalBinary: got %x, expected %x", b, ek.Bytes())
+	}
+
+	type config struct {
+		DK *DecapsulationKey
+		EK *EncapsulationKey
+	}
+	data, err := json.Marshal(config{DK: dk, EK: ek})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"DK":"` + base64.StdEncoding.EncodeToString(seed) + `","EK":"` +
+		base64.StdEncoding.EncodeToString(ek.Bytes()) + `"}`
+	if string(data) != want {
+		t.Errorf("json.Marshal: got %s, expected %s", data, want)
+	}
+	var c config
+	if err := json.Unmarshal(data, &c); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(c.DK.Bytes(), seed) {
+		t.Errorf("json.Unmarshal: got %x, expected %x", c.DK.Bytes(), seed)
+	}
+	if !bytes.Equal(c.EK.Bytes(), ek.Bytes()) {
+		t.Errorf("json.Unmarshal: got a different encapsulation key")
+	}
+
+	var dk1 DecapsulationKey
+	if err := dk1.UnmarshalBinary(seed); err != nil {
+		t.Fatal(err)
+	}
+	if err := dk1.UnmarshalBinary(seed[1:]); !errors.Is(err, ErrInvalidSeed) {
+		t.Errorf("UnmarshalBinary: got %v, expected %v", err, ErrInvalidSeed)
+	}
+	if err := dk1.UnmarshalText([]byte(base64.RawStdEncoding.EncodeToString(seed))); !errors.Is(err, ErrInvalidSeed) {
+		t.Errorf("UnmarshalText: got %v, expected %v", err, ErrInvalidSeed)
+	}
+	if !bytes.Equal(dk1.Bytes(), seed) {
+		t.Errorf("UnmarshalBinary: key changed after error")
+	}
+	var ek1 EncapsulationKey
+	if err := ek1.UnmarshalText([]byte(base64.StdEncoding.EncodeToString(seed))); !errors.Is(err, ErrInvalidEncapsulationKey) {
+		t.Errorf("UnmarshalText: got %v, expected %v", err, ErrInvalidEncapsulationKey)
+	}
+
+	dk.Destroy()
+	if _, err := dk.MarshalText(); !errors.Is(err, ErrKeyDestroyed) {
+		t.Errorf("MarshalText: got %v, expected %v", err, ErrKeyDestroyed)
+	}
+}
+
+func TestRedaction(t *testing.T) {
+	seed := make([]byte, SeedSize)
+	seed[0] = 0xaa
+	dk, err := NewKeyFromSeed(seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Only pointers are redacted, as keys should not be held by value.
+	type wrapper struct {
+		Ptr *DecapsulationKey
+	}
+	for _, format := range []string{"%v", "%+v", "%#v", "%s", "%q", "%x", "%X", "%d"} {
+		for _, arg := range []any{dk, wrapper{Ptr: dk}, []*DecapsulationKey{dk}} {
+			out := fmt.Sprintf(format, arg)
+			if bytes.Contains(bytes.ToLower([]byte(out)), []byte(hex.EncodeToString(seed[:4]))) ||
+				bytes.Contains([]byte(out), []byte("170")) {
+				t.Errorf("Sprintf(%q, %T) leaked the seed: %s", format, arg, out)
+			}
+		}
+	}
+	if got := fmt.Sprint(dk); got != "xwing.DecapsulationKey(REDACTED)" {
+		t.Errorf("Sprint: got %q", got)
+	}
+	if got := fmt.Sprintf("%#v", dk); got != "xwing.DecapsulationKey(REDACTED)" {
+		t.Errorf("Sprintf(%%#v): got %q", got)
+	}
+	dk.Destroy()
+	if got := dk.String(); got != "xwing.DecapsulationKey(REDACTED)" {
+		t.Errorf("String after Destroy: got %q", got)
+	}
+}
@@ -0,0 +1,73 @@
+package xwing
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+
+	"filippo.io/mlkem768/internal/jwk"
+)
+
+// The JSON Web Key (RFC 7517) representation implemented in this file is the
+// Algorithm Key Pair (AKP) key type of [draft-ietf-jose-pqc-kem], where "pub"
+// is the encapsulation key and "priv" is the 32-byte seed, both base64url
+// encoded without padding.
+//
+// [draft-ietf-jose-pqc-kem]: https://datatracker.ietf.org/doc/draft-ietf-jose-pqc-kem/
+
+const jwkAlgorithm = "X-Wing"
+
+// MarshalJWKPrivateKey encodes dk as a JSON Web Key with the "priv" member set
+// to the seed, and the "pub" member set to the encapsulation key.
+func MarshalJWKPrivateKey(dk *DecapsulationKey) ([]byte, error) {
+	if dk.destroyed {
+		return nil, ErrKeyDestroyed
+	}
+	return jwk.MarshalKey(jwkAlgorithm, dk.EncapsulationKey(), dk.Bytes()), nil
+}
+
+// ParseJWKPrivateKey parses a JSON Web Key produced by [MarshalJWKPrivateKey].
+// The "pub" member must match the key derived from the "priv" member.
+func ParseJWKPrivateKey(data []byte) (*DecapsulationKey, error) {
+	pub, seed, err := jwk.ParseKey(data, jwkAlgorithm)
+	if err != nil {
+		return nil, fmt.Errorf("xwing: %w", err)
+	}
+	if seed == nil {
+		return nil, errors.New("xwing: JWK has no private key")
+	}
+	dk, err := NewKeyFromSeed(seed)
+	if err != nil {
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare(pub, dk.EncapsulationKey()) != 1 {
+		dk.Destroy()
+		return nil, errors.New("xwing: JWK public key doesn't match the private key")
+	}
+	return dk, nil
+}
+
+// MarshalJWKPublicKey encodes ek as a JSON Web Key.
+func MarshalJWKPublicKey(ek *EncapsulationKey) ([]byte, error) {
+	return jwk.MarshalKey(jwkAlgorithm, ek.Bytes(), nil), nil
+}
+
+// ParseJWKPublicKey parses a JSON Web Key produced by [MarshalJWKPublicKey].
+// To avoid mishandling secrets, it rejects keys with a "priv" member.
+func ParseJWKPublicKey(data []byte) (*EncapsulationKey, error) {
+	pub, priv, err := jwk.ParseKey(data, jwkAlgorithm)
+	if err != nil {
+		return nil, fmt.Errorf("xwing: %w", err)
+	}
+	if priv != nil {
+		return nil, errors.New("xwing: JWK unexpectedly has a private key")
+	}
+	return NewEncapsulationKey(pub)
+}
+
+// JWKThumbprint returns the JWK Thumbprint (RFC 7638) of ek, the base64url
+// encoding of the SHA-256 hash of its required JWK members. It is suitable for
+// use as a "kid" key identifier.
+func JWKThumbprint(ek *EncapsulationKey) string {
+	return jwk.Thumbprint(jwkAlgorithm, ek.Bytes())
+}
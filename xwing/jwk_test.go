@@ -0,0 +1,89 @@
+package xwing
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+func TestJWK(t *testing.T) {
+	seed := make([]byte, SeedSize)
+	dk, err := NewKeyFromSeed(seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ek, err := NewEncapsulationKey(dk.EncapsulationKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+	b64 := base64.RawURLEncoding
+
+	priv, err := MarshalJWKPrivateKey(dk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"kty":"AKP","alg":"X-Wing","pub":"` + b64.EncodeToString(ek.Bytes()) +
+		`","priv":"` + b64.EncodeToString(seed) + `"}`
+	if string(priv) != want {
+		t.Errorf("MarshalJWKPrivateKey: got %s, expected %s", priv, want)
+	}
+	dk1, err := ParseJWKPrivateKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(dk1.Bytes(), seed) {
+		t.Errorf("ParseJWKPrivateKey: got %x, expected %x", dk1.Bytes(), seed)
+	}
+
+	pub, err := MarshalJWKPublicKey(ek)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ek1, err := ParseJWKPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(ek1.Bytes(), ek.Bytes()) {
+		t.Errorf("ParseJWKPublicKey: got %x, expected %x", ek1.Bytes(), ek.Bytes())
+	}
+	if _, err := ParseJWKPublicKey(priv); err == nil {
+		t.Errorf("ParseJWKPublicKey: expected error for private key")
+	}
+	if _, err := ParseJWKPrivateKey(pub); err == nil {
+		t.Errorf("ParseJWKPrivateKey: expected error for public key")
+	}
+	mlkemJWK := bytes.Replace(priv, []byte(`"X-Wing"`), []byte(`"ML-KEM-768"`), 1)
+	if _, err := ParseJWKPrivateKey(mlkemJWK); err == nil {
+		t.Errorf("ParseJWKPrivateKey: expected error for ML-KEM-768 key")
+	}
+
+	// encoding/json would match member names case-insensitively and keep the
+	// last of duplicate members, letting parsers disagree on the key.
+	otherEK := bytes.Clone(ek.Bytes())
+	otherEK[len(otherEK)-1] ^= 1
+	otherPub := `"pub":"` + b64.EncodeToString(otherEK) + `"`
+	for name, data := range map[string]string{
+		"private duplicate pub": strings.Replace(string(priv), "{", "{"+otherPub+",", 1),
+		"private duplicate kty": strings.Replace(string(priv), "{", `{"kty":"AKP",`, 1),
+		"private case kty":      strings.Replace(string(priv), `"kty"`, `"Kty"`, 1),
+		"private case priv":     strings.Replace(string(priv), `"priv"`, `"PRIV"`, 1),
+		"public duplicate pub":  strings.Replace(string(pub), "{", "{"+otherPub+",", 1),
+		"public case pub":       strings.Replace(string(pub), `"pub"`, `"Pub"`, 1),
+		"public case priv":      strings.Replace(string(pub), "}", `,"Priv":"`+b64.EncodeToString(seed)+`"}`, 1),
+	} {
+		if _, err := ParseJWKPrivateKey([]byte(data)); err == nil {
+			t.Errorf("%s: ParseJWKPrivateKey: expected error", name)
+		}
+		if _, err := ParseJWKPublicKey([]byte(data)); err == nil {
+			t.Errorf("%s: ParseJWKPublicKey: expected error", name)
+		}
+	}
+
+	required := `{"alg":"X-Wing","kty":"AKP","pub":"` + b64.EncodeToString(ek.Bytes()) + `"}`
+	h := sha256.Sum256([]byte(required))
+	if got := JWKThumbprint(ek); got != b64.EncodeToString(h[:]) {
+		t.Errorf("JWKThumbprint: got %s, expected %s", got, b64.EncodeToString(h[:]))
+	}
+}
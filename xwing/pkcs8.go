@@ -0,0 +1,119 @@
+package xwing
+
+import (
+	"crypto/subtle"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+)
+
+// oidXWing is id-XWing, as assigned by the draft.
+var oidXWing = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 62253, 25722}
+
+// pkcs8 is the OneAsymmetricKey structure of RFC 5958, which is compatible
+// with the PrivateKeyInfo structure of PKCS #8 (RFC 5208).
+type pkcs8 struct {
+	Version    int
+	Algo       pkix.AlgorithmIdentifier
+	PrivateKey []byte
+	Attributes asn1.RawValue  `asn1:"optional,tag:0"`
+	PublicKey  asn1.BitString `asn1:"optional,tag:1"`
+}
+
+// spki is the SubjectPublicKeyInfo structure of RFC 5280.
+type spki struct {
+	Algorithm pkix.AlgorithmIdentifier
+	PublicKey asn1.BitString
+}
+
+// MarshalPKCS8PrivateKey encodes dk as a PKCS #8 (RFC 5208 and RFC 5958)
+// private key, with the id-XWing algorithm identifier and absent parameters.
+// The privateKey field holds the 32-byte seed.
+func MarshalPKCS8PrivateKey(dk *DecapsulationKey) ([]byte, error) {
+	if dk.destroyed {
+		return nil, ErrKeyDestroyed
+	}
+	return asn1.Marshal(pkcs8{
+		Algo:       pkix.AlgorithmIdentifier{Algorithm: oidXWing},
+		PrivateKey: dk.sk[:],
+	})
+}
+
+// ParsePKCS8PrivateKey parses a PKCS #8 (RFC 5208 and RFC 5958) private key
+// with the id-XWing algorithm identifier, as produced by
+// [MarshalPKCS8PrivateKey]. If the optional public key is present, it must
+// match the private key, and the version must be v2 as required by RFC 5958.
+// Otherwise, it must be v1.
+func ParsePKCS8PrivateKey(der []byte) (*DecapsulationKey, error) {
+	var p pkcs8
+	if rest, err := asn1.Unmarshal(der, &p); err != nil {
+		return nil, fmt.Errorf("xwing: invalid PKCS #8 private key: %w", err)
+	} else if len(rest) != 0 {
+		return nil, errors.New("xwing: trailing data after PKCS #8 private key")
+	}
+	// RFC 5958 requires version v2 (1) if and only if the public key is present.
+	// An empty public key still has a non-nil Bytes field.
+	hasPublicKey := p.PublicKey.Bytes != nil
+	switch {
+	case p.Version != 0 && p.Version != 1:
+		return nil, fmt.Errorf("xwing: unsupported PKCS #8 version %d", p.Version)
+	case hasPublicKey && p.Version != 1:
+		return nil, errors.New("xwing: PKCS #8 private key with a public key is not version v2")
+	case !hasPublicKey && p.Version != 0:
+		return nil, errors.New("xwing: PKCS #8 private key without a public key is not version v1")
+	}
+	if err := checkAlgorithm(p.Algo); err != nil {
+		return nil, err
+	}
+	dk, err := NewKeyFromSeed(p.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+	if hasPublicKey {
+		if p.PublicKey.BitLength != 8*len(p.PublicKey.Bytes) ||
+			subtle.ConstantTimeCompare(p.PublicKey.Bytes, dk.pk[:]) != 1 {
+			dk.Destroy()
+			return nil, errors.New("xwing: PKCS #8 public key doesn't match the private key")
+		}
+	}
+	return dk, nil
+}
+
+// MarshalPKIXPublicKey encodes ek as a SubjectPublicKeyInfo (RFC 5280) with
+// the id-XWing algorithm identifier and absent parameters.
+func MarshalPKIXPublicKey(ek *EncapsulationKey) ([]byte, error) {
+	return asn1.Marshal(spki{
+		Algorithm: pkix.AlgorithmIdentifier{Algorithm: oidXWing},
+		PublicKey: asn1.BitString{Bytes: ek.Bytes(), BitLength: 8 * EncapsulationKeySize},
+	})
+}
+
+// ParsePKIXPublicKey parses a SubjectPublicKeyInfo (RFC 5280) with the id-XWing
+// algorithm identifier. The encapsulation key is checked like by
+// [NewEncapsulationKey].
+func ParsePKIXPublicKey(der []byte) (*EncapsulationKey, error) {
+	var p spki
+	if rest, err := asn1.Unmarshal(der, &p); err != nil {
+		return nil, fmt.Errorf("xwing: invalid SubjectPublicKeyInfo: %w", err)
+	} else if len(rest) != 0 {
+		return nil, errors.New("xwing: trailing data after SubjectPublicKeyInfo")
+	}
+	if err := checkAlgorithm(p.Algorithm); err != nil {
+		return nil, err
+	}
+	if p.PublicKey.BitLength != 8*len(p.PublicKey.Bytes) {
+		return nil, ErrInvalidEncapsulationKey
+	}
+	return NewEncapsulationKey(p.PublicKey.Bytes)
+}
+
+func checkAlgorithm(algo pkix.AlgorithmIdentifier) error {
+	if !algo.Algorithm.Equal(oidXWing) {
+		return fmt.Errorf("xwing: unsupported algorithm %v", algo.Algorithm)
+	}
+	if len(algo.Parameters.FullBytes) != 0 {
+		return errors.New("xwing: unexpected id-XWing parameters")
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package xwing
+
+import (
+	"bytes"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/hex"
+	"errors"
+	"testing"
+)
+
+func TestPKCS8(t *testing.T) {
+	seed := make([]byte, SeedSize)
+	for i := range seed {
+		seed[i] = byte(i)
+	}
+	dk, err := NewKeyFromSeed(seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want, _ := hex.DecodeString("3034020100300d060b2b0601040183e62d81c87a0420" + hex.EncodeToString(seed))
+	der, err := MarshalPKCS8PrivateKey(dk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(der, want) {
+		t.Errorf("MarshalPKCS8PrivateKey: got %x, expected %x", der, want)
+	}
+	dk1, err := ParsePKCS8PrivateKey(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(dk1.Bytes(), seed) {
+		t.Errorf("ParsePKCS8PrivateKey: got %x, expected %x", dk1.Bytes(), seed)
+	}
+
+	marshal := func(version int, oid asn1.ObjectIdentifier, privateKey, publicKey []byte) []byte {
+		t.Helper()
+		der, err := asn1.Marshal(struct {
+			Version    int
+			Algo       pkix.AlgorithmIdentifier
+			PrivateKey []byte
+			PublicKey  asn1.BitString `asn1:"optional,tag:1"`
+		}{version, pkix.AlgorithmIdentifier{Algorithm: oid},
+			privateKey, asn1.BitString{Bytes: publicKey, BitLength: 8 * len(publicKey)}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return der
+	}
+	badEK := dk.EncapsulationKey()
+	badEK[0] ^= 1
+	if _, err := ParsePKCS8PrivateKey(marshal(1, oidXWing, seed, dk.EncapsulationKey())); err != nil {
+		t.Errorf("public key: %v", err)
+	}
+	for name, der := range map[string][]byte{
+		"wrong public key":      marshal(1, oidXWing, seed, badEK),
+		"short seed":            marshal(0, oidXWing, seed[1:], nil),
+		"wrong OID":             marshal(0, asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 4, 2}, seed, nil),
+		"version 2":             marshal(2, oidXWing, seed, nil),
+		"v2 without public key": marshal(1, oidXWing, seed, nil),
+		"v1 with public key":    marshal(0, oidXWing, seed, dk.EncapsulationKey()),
+		"empty public key":      marshal(1, oidXWing, seed, []byte{}),
+		"trailing data":         append(bytes.Clone(want), 0),
+	} {
+		if _, err := ParsePKCS8PrivateKey(der); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+
+	dk.Destroy()
+	if _, err := MarshalPKCS8PrivateKey(dk); !errors.Is(err, ErrKeyDestroyed) {
+		t.Errorf("MarshalPKCS8PrivateKey: got %v, expected %v", err, ErrKeyDestroyed)
+	}
+}
+
+func TestPKIX(t *testing.T) {
+	dk, err := NewKeyFromSeed(make([]byte, SeedSize))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ek, err := NewEncapsulationKey(dk.EncapsulationKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want, _ := hex.DecodeString("308204d4300d060b2b0601040183e62d81c87a038204c100" + hex.EncodeToString(ek.Bytes()))
+	der, err := MarshalPKIXPublicKey(ek)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(der, want) {
+		t.Errorf("MarshalPKIXPublicKey: got %x, expected %x", der, want)
+	}
+	ek1, err := ParsePKIXPublicKey(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(ek1.Bytes(), ek.Bytes()) {
+		t.Errorf("ParsePKIXPublicKey: got %x, expected %x", ek1.Bytes(), ek.Bytes())
+	}
+
+	unreduced := bytes.Clone(der)
+	unreduced[len(der)-EncapsulationKeySize] = 0xff
+	unreduced[len(der)-EncapsulationKeySize+1] = 0xff
+	if _, err := ParsePKIXPublicKey(unreduced); !errors.Is(err, ErrInvalidEncapsulationKey) {
+		t.Errorf("unreduced key: got %v, expected %v", err, ErrInvalidEncapsulationKey)
+	}
+	wrongOID := bytes.Clone(der)
+	wrongOID[18] ^= 1
+	if _, err := ParsePKIXPublicKey(wrongOID); err == nil {
+		t.Errorf("wrong OID: expected error")
+	}
+	if _, err := ParsePKIXPublicKey(append(der, 0)); err == nil {
+		t.Errorf("trailing data: expected error")
+	}
+}
@@ -0,0 +1,106 @@
+package xwing
+
+import (
+	"crypto/sha3"
+	"encoding/hex"
+	"fmt"
+)
+
+// The known-answer test uses the key generated from the seed 00 01 02 ... 1f.
+// EncapsulateDerand with the eseed 20 21 22 ... 5f produces selfTestCiphertext
+// and selfTestSharedKey, and with its first byte inverted, the ciphertext is
+// rejected by ML-KEM-768 and decapsulates to selfTestRejectedKey.
+const (
+	selfTestEncapsulationKeyHash = "02ed14d55121ca47e2aa279a7fdba9867f7d9bbc3c5ab4f004f94354565c8158"
+	selfTestSharedKey            = "9ef8c4373f751b482022f88f3e8cceeb4815a3c1afbc784324ac9eeb50932023"
+	selfTestRejectedKey          = "26d0f2d267b17282dcdc44d8f891ce2a09f8e345b9498c9325236022812766df"
+
+	selfTestCiphertext = "" +
+		"2300731f60f7ffcc2a3724204e3046f37eab7bbf4358aaa42ecdbafce51648df" +
+		"c699ca882d7f876b1bd55d777e84c3c38d55c0f2892817211970a19df6071bfd" +
+		"daeaefe64e540c3a14d426b248fbadc5fa0eb2f6a322d9de0a7bdc9a1bd5f035" +
+		"4656fb7b33c30a0adcd42624714575a1c844af709270ff986ed32c7cfc0c2f73" +
+		"e67fbca7bd176b6a89b6b3a8115358d5bf17ae72f546a0f652e50bcd3031b002" +
+		"0c9cd0621def6823eedfbaa3913e2da930b68076b1f8c545cc85afb4b7451adb" +
+		"2768f86e2f8ce27a72011938c99d5b0f2a7c79b1b65c3a66e22bf49194b6bcbf" +
+		"20f683b6cd3d07a149c081b7ae2510967d484e5cd912d4a5fe04d7d214c9683e" +
+		"7d8290a747671222402de4e363fca77ebcbb15aba5d1fd6e54c4f169f3d05b75" +
+		"8a4b300a289a7aa44662c7498da9357d9dbf3e1637a2074dd6bcd33edcbbbceb" +
+		"b7828d73149e0adc2fd1c7de0f55c1e65cde1dd8a670d6d44e4d14ca84010d7f" +
+		"2117ae49af1021c5b58a22d7367b7ef17adf5b2b61d31f8b860c8d9e40c9e337" +
+		"26fec7391280a65561b9fe5abfff35c69a1d821099ff0ceef76db86bccd81d43" +
+		"8c38b40c075bbb1abbaed7883bab41832e6e2a0f24060336cc987c20696f6571" +
+		"4e37a6f6f2120d2a2e57d993f12c5ab2fc5aaa63ae028133c77d7d7bb67d7b9b" +
+		"7aae8f954588947364a4d49d74288cb45b6d6a5286cbdea8aeff45d118cf2ba8" +
+		"f184717faf82dc3f35492d4b3f089b272d9a95c747f9d285c780799c84d0ba89" +
+		"6b442bf92d45ea445d16f7fbe55f100b43162e7e5f49758ab40ddf0a0ba015b1" +
+		"ba308085d147917ee89a64799a81c5dc71579886d5eea8332936c8e4c18525de" +
+		"54d5b1d0daf95537e799dc35502e5ddc034ee3ec106cc1b9191d27e3c3e5413c" +
+		"3ee72a34ac991f7a114a9b88184ba20a29475f1c2bd560419e066358829cb5ae" +
+		"0354ec643d4771847452c89b2b149b1a07998ce95f1b27d3e153c0c059af0ad3" +
+		"adea77dfe89fe8fc3c15907f7013920d9ae102caa0a4a7eff15ddabf11dd878e" +
+		"09e92faa84899275dc901b950a4c78d14638a17e3c2324483f339aada1b33a11" +
+		"e64d836466fee6b8e2c043d469f98a73c2760d58b3593de80edb29ef73b65df6" +
+		"e4cff8ce2ecb9623d7da837e6650605d4a4bbe74ec81a689144c3c51423cbe52" +
+		"d671582a68eee2e9f0706304eb5f55b3f9dcd3aa6337b3f536236b9dafb5d6c3" +
+		"2a90575732ede66eb67caf32b7d2cb1772f52903d0bf73ee2be046c4824a8d55" +
+		"14475b281ee9775dc6f380b5ba96d86ea735639492121c6cabe973f794f4fe4b" +
+		"804a31c70a0aa4ea9635aefe6930ca74ebc27739a9b0b4d1e286807bd0771823" +
+		"d02f23fa216df067d0537c24072886bc214955b73c7937921c16b1895ae8825b" +
+		"c69b6da9387663055c3b10e7d6e81b70564365cc7e94e519e073de4d6fcbd9f8" +
+		"f3c3641b5c0de9e29b991b73433238877a8658af3b55cb683f9e662895b0c37c" +
+		"b50b46014a9ceb9aca96a601be901e5b3d8d1ede052b871020cb4f455ab96917" +
+		"79a631eede1bf9c98f12032cdeadd0e7a079398fc786b88cc846ec89af85a51a"
+)
+
+// SelfTest runs a known-answer test of key generation, encapsulation, and
+// decapsulation, including ML-KEM-768 implicit rejection, and returns
+// [ErrSelfTest] if any result doesn't match the expected value.
+//
+// SelfTest is meant to be called at startup by applications that need to check
+// the implementation before using it.
+func SelfTest() error {
+	seed := make([]byte, SeedSize)
+	for i := range seed {
+		seed[i] = byte(i)
+	}
+	eseed := make([]byte, 64)
+	for i := range eseed {
+		eseed[i] = byte(SeedSize + i)
+	}
+
+	dk, err := NewKeyFromSeed(seed)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrSelfTest, err)
+	}
+	defer dk.Destroy()
+	pk := dk.EncapsulationKey()
+	if h := sha3.Sum256(pk); hex.EncodeToString(h[:]) != selfTestEncapsulationKeyHash {
+		return fmt.Errorf("%w: key generation known answer mismatch", ErrSelfTest)
+	}
+
+	ct, ss, err := EncapsulateDerand(pk, eseed)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrSelfTest, err)
+	}
+	if hex.EncodeToString(ct) != selfTestCiphertext || hex.EncodeToString(ss) != selfTestSharedKey {
+		return fmt.Errorf("%w: encapsulation known answer mismatch", ErrSelfTest)
+	}
+
+	ss, err = Decapsulate(dk, ct)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrSelfTest, err)
+	}
+	if hex.EncodeToString(ss) != selfTestSharedKey {
+		return fmt.Errorf("%w: decapsulation known answer mismatch", ErrSelfTest)
+	}
+	ct[0] ^= 0xff
+	ss, err = Decapsulate(dk, ct)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrSelfTest, err)
+	}
+	if hex.EncodeToString(ss) != selfTestRejectedKey {
+		return fmt.Errorf("%w: implicit rejection known answer mismatch", ErrSelfTest)
+	}
+	return nil
+}
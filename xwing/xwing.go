@@ -2,6 +2,14 @@
 // method X-Wing, which combines X25519, ML-KEM-768, and SHA3-256 as specified
 // in [draft-connolly-cfrg-xwing-kem].
 //
+// X25519 is not a FIPS 140-3 approved algorithm, so in FIPS 140-only mode
+// (GODEBUG=fips140=only) all operations that use it return an error.
+//
+// The COSE encodings, such as [MarshalCOSEPublicKey] and [SealCOSEEncrypt], use
+// a private-use algorithm identifier and a private recipient format. They are
+// not interoperable with other COSE implementations, and are only meant for
+// exchanging keys and messages with other users of this package.
+//
 // [draft-connolly-cfrg-xwing-kem]: https://www.ietf.org/archive/id/draft-connolly-cfrg-xwing-kem-07.html
 package xwing
 
@@ -12,6 +20,11 @@ import (
 	"crypto/rand"
 	"crypto/sha3"
 	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"filippo.io/mlkem768"
 )
 
 const (
@@ -21,31 +34,92 @@ const (
 	SeedSize             = 32
 )
 
+// Errors returned by this package, for use with [errors.Is].
+var (
+	ErrInvalidSeed             = errors.New("xwing: invalid seed length")
+	ErrInvalidRandomness       = errors.New("xwing: invalid eseed length")
+	ErrInvalidCiphertextLength = errors.New("xwing: invalid ciphertext length")
+	ErrInvalidEncapsulationKey = errors.New("xwing: invalid encapsulation key")
+	ErrKeyDestroyed            = errors.New("xwing: use of destroyed DecapsulationKey")
+
+	// ErrLowOrderPoint is returned by NewEncapsulationKey and encapsulation
+	// when the X25519 component of the encapsulation key is a low-order point,
+	// which would make the X25519 shared secret all zeroes. Decapsulation
+	// never returns it.
+	ErrLowOrderPoint = errors.New("xwing: low order X25519 point")
+
+	// ErrPairwiseConsistency is returned by GenerateKeyWithPCT if the
+	// generated key fails the pairwise consistency test.
+	ErrPairwiseConsistency = errors.New("xwing: pairwise consistency test failed")
+
+	// ErrSelfTest is returned by SelfTest if the implementation produces
+	// unexpected results.
+	ErrSelfTest = errors.New("xwing: self-test failed")
+)
+
 // A DecapsulationKey is the secret key used to decapsulate a shared key from a
 // ciphertext. It includes various precomputed values.
+//
+// Keys should be held by pointer, as returned by the constructors. Copying a
+// DecapsulationKey value duplicates the secret key where
+// [DecapsulationKey.Destroy] can't reach it, and only pointers are redacted
+// when formatted by the fmt package.
 type DecapsulationKey struct {
 	sk  [SeedSize]byte
 	skM *mlkem.DecapsulationKey768
 	skX *ecdh.PrivateKey
 	pk  [EncapsulationKeySize]byte
+
+	destroyed bool
 }
 
 // Bytes returns the decapsulation key as a 32-byte seed.
 func (dk *DecapsulationKey) Bytes() []byte {
+	dk.checkDestroyed()
 	return bytes.Clone(dk.sk[:])
 }
 
 // EncapsulationKey returns the public encapsulation key necessary to produce
 // ciphertexts.
 func (dk *DecapsulationKey) EncapsulationKey() []byte {
+	dk.checkDestroyed()
 	return bytes.Clone(dk.pk[:])
 }
 
+// Destroy zeroizes the decapsulation key. After Destroy, Decapsulate returns an
+// error, and the methods of dk panic.
+//
+// The seed is overwritten in place. The expanded ML-KEM-768 and X25519 keys are
+// held by the crypto/mlkem and crypto/ecdh packages, which don't support
+// zeroization, so Destroy can only drop the references to them.
+func (dk *DecapsulationKey) Destroy() {
+	clear(dk.sk[:])
+	dk.skM = nil
+	dk.skX = nil
+	clear(dk.pk[:])
+	dk.destroyed = true
+}
+
+func (dk *DecapsulationKey) checkDestroyed() {
+	if dk.destroyed {
+		panic(ErrKeyDestroyed)
+	}
+}
+
 // GenerateKey generates a new decapsulation key, drawing random bytes from
 // crypto/rand. The decapsulation key must be kept secret.
 func GenerateKey() (*DecapsulationKey, error) {
+	return GenerateKeyFromReader(rand.Reader)
+}
+
+// GenerateKeyFromReader works like [GenerateKey] but draws random bytes from r
+// instead of crypto/rand. It reads exactly [SeedSize] bytes from r.
+//
+// r must be a cryptographically secure random source, as its output is the
+// decapsulation key seed.
+func GenerateKeyFromReader(r io.Reader) (*DecapsulationKey, error) {
 	sk := make([]byte, SeedSize)
-	if _, err := rand.Read(sk); err != nil {
+	if _, err := io.ReadFull(r, sk); err != nil {
 		return nil, err
 	}
 	return NewKeyFromSeed(sk)
@@ -53,9 +127,12 @@ func GenerateKey() (*DecapsulationKey, error) {
 
 // NewKeyFromSeed deterministically generates a decapsulation key from a 32-byte
 // seed. The seed must be uniformly random.
+//
+// The seed is expanded with SHAKE256 into the 64-byte ML-KEM-768 seed followed
+// by the 32-byte X25519 private key, as specified in the draft.
 func NewKeyFromSeed(sk []byte) (*DecapsulationKey, error) {
 	if len(sk) != SeedSize {
-		return nil, errors.New("xwing: invalid seed length")
+		return nil, ErrInvalidSeed
 	}
 
 	s := sha3.NewSHAKE256()
@@ -90,14 +167,112 @@ const xwingLabel = (`` +
 	`\./` +
 	`/^\`)
 
-func combiner(ssM, ssX, ctX, pkX []byte) []byte {
+// combiner implements the X-Wing combiner,
+//
+//	SHA3-256(ss_M || ss_X || ct_X || pk_X || XWingLabel)
+func combiner(ss *[SharedKeySize]byte, ssM, ssX, ctX, pkX []byte) {
 	h := sha3.New256()
 	h.Write(ssM)
 	h.Write(ssX)
 	h.Write(ctX)
 	h.Write(pkX)
 	h.Write([]byte(xwingLabel))
-	return h.Sum(nil)
+	h.Sum(ss[:0])
+}
+
+// An EncapsulationKey is the public key used to produce ciphertexts to be
+// decapsulated by the corresponding [DecapsulationKey]. It holds the parsed
+// ML-KEM-768 and X25519 public keys, so it should be reused for repeated
+// encapsulations.
+type EncapsulationKey struct {
+	pkM *mlkem.EncapsulationKey768
+	pkX *ecdh.PublicKey
+	pk  [EncapsulationKeySize]byte
+}
+
+// NewEncapsulationKey parses an encapsulation key from its encoded form.
+// If the encapsulation key is not valid, NewEncapsulationKey returns an error.
+//
+// If the X25519 component is a low-order point, encapsulation to the key would
+// always fail, so NewEncapsulationKey returns [ErrLowOrderPoint].
+func NewEncapsulationKey(encapsulationKey []byte) (*EncapsulationKey, error) {
+	if len(encapsulationKey) != EncapsulationKeySize {
+		return nil, ErrInvalidEncapsulationKey
+	}
+
+	pkM, err := mlkem.NewEncapsulationKey768(encapsulationKey[:mlkem.EncapsulationKeySize768])
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidEncapsulationKey, err)
+	}
+	pkX, err := ecdh.X25519().NewPublicKey(encapsulationKey[mlkem.EncapsulationKeySize768:])
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidEncapsulationKey, err)
+	}
+	if _, err := lowOrderCheckKey().ECDH(pkX); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrLowOrderPoint, err)
+	}
+
+	ek := &EncapsulationKey{pkM: pkM, pkX: pkX}
+	copy(ek.pk[:], encapsulationKey)
+	return ek, nil
+}
+
+// lowOrderCheckKey returns a fixed X25519 private key, used to detect low-order
+// public keys. crypto/ecdh rejects the all-zero shared secret that any private
+// key produces with them, since X25519 clamps scalars to a multiple of the
+// cofactor.
+var lowOrderCheckKey = sync.OnceValue(func() *ecdh.PrivateKey {
+	k, err := ecdh.X25519().NewPrivateKey(bytes.Repeat([]byte{0x42}, 32))
+	if err != nil {
+		panic("xwing: internal error: " + err.Error())
+	}
+	return k
+})
+
+// Bytes returns the encapsulation key as a byte slice.
+func (ek *EncapsulationKey) Bytes() []byte {
+	return bytes.Clone(ek.pk[:])
+}
+
+// Encapsulate generates a shared key and an associated ciphertext, drawing
+// random bytes from crypto/rand.
+//
+// The shared key must be kept secret.
+func (ek *EncapsulationKey) Encapsulate() (ciphertext, sharedKey []byte, err error) {
+	ct := new([CiphertextSize]byte)
+	ss := new([SharedKeySize]byte)
+	if err := EncapsulateTo(ct, ss, ek); err != nil {
+		return nil, nil, err
+	}
+	return ct[:], ss[:], nil
+}
+
+// EncapsulateTo works like [EncapsulationKey.Encapsulate] but writes the
+// ciphertext and shared key to the provided arrays.
+//
+// Note that the crypto/ecdh and crypto/mlkem packages might still allocate
+// internally.
+func EncapsulateTo(ciphertext *[CiphertextSize]byte, sharedKey *[SharedKeySize]byte, ek *EncapsulationKey) error {
+	ephemeralKey, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return err
+	}
+	ssM, ctM := ek.pkM.Encapsulate()
+	return ek.encapsulate(ciphertext, sharedKey, ephemeralKey, ssM, ctM)
+}
+
+func (ek *EncapsulationKey) encapsulate(ct *[CiphertextSize]byte, ss *[SharedKeySize]byte, ephemeralKey *ecdh.PrivateKey, ssM, ctM []byte) error {
+	ssX, err := ephemeralKey.ECDH(ek.pkX)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrLowOrderPoint, err)
+	}
+
+	ctX := ct[mlkem.CiphertextSize768:]
+	copy(ct[:], ctM)
+	copy(ctX, ephemeralKey.PublicKey().Bytes())
+	pkX := ek.pk[mlkem.EncapsulationKeySize768:]
+	combiner(ss, ssM, ssX, ctX, pkX)
+	return nil
 }
 
 // Encapsulate generates a shared key and an associated ciphertext from an
@@ -106,45 +281,90 @@ func combiner(ssM, ssX, ctX, pkX []byte) []byte {
 //
 // The shared key must be kept secret.
 func Encapsulate(encapsulationKey []byte) (ciphertext, sharedKey []byte, err error) {
-	if len(encapsulationKey) != EncapsulationKeySize {
-		return nil, nil, errors.New("xwing: invalid encapsulation key size")
+	ek, err := NewEncapsulationKey(encapsulationKey)
+	if err != nil {
+		return nil, nil, err
 	}
+	return ek.Encapsulate()
+}
 
-	pkM := encapsulationKey[:mlkem.EncapsulationKeySize768]
-	pkX := encapsulationKey[mlkem.EncapsulationKeySize768:]
-
-	ephemeralKey, err := ecdh.X25519().GenerateKey(rand.Reader)
-	if err != nil {
+// EncapsulateWithReader works like [Encapsulate] but draws random bytes from r
+// instead of crypto/rand. It reads exactly 64 bytes from r, which are used as
+// the eseed input to [EncapsulateDerand].
+//
+// r must be a cryptographically secure random source, as its output determines
+// the shared key.
+func EncapsulateWithReader(r io.Reader, encapsulationKey []byte) (ciphertext, sharedKey []byte, err error) {
+	eseed := make([]byte, 64)
+	if _, err := io.ReadFull(r, eseed); err != nil {
 		return nil, nil, err
 	}
-	peerKey, err := ecdh.X25519().NewPublicKey(pkX)
+	return EncapsulateDerand(encapsulationKey, eseed)
+}
+
+// EncapsulateDerand works like [Encapsulate] but accepts the random bytes as an
+// input. It should only be used for testing.
+//
+// eseed must be 64 bytes long: the first 32 bytes are the ML-KEM-768
+// encapsulation randomness, and the last 32 bytes are the X25519 ephemeral
+// private key, as specified in the draft.
+func EncapsulateDerand(encapsulationKey, eseed []byte) (ciphertext, sharedKey []byte, err error) {
+	if len(eseed) != 64 {
+		return nil, nil, ErrInvalidRandomness
+	}
+	ek, err := NewEncapsulationKey(encapsulationKey)
 	if err != nil {
 		return nil, nil, err
 	}
-	ctX := ephemeralKey.PublicKey().Bytes()
-	ssX, err := ephemeralKey.ECDH(peerKey)
+
+	ephemeralKey, err := ecdh.X25519().NewPrivateKey(eseed[32:])
 	if err != nil {
 		return nil, nil, err
 	}
 
-	ek, err := mlkem.NewEncapsulationKey768(pkM)
+	pkM := encapsulationKey[:mlkem.EncapsulationKeySize768]
+	ctM, ssM, err := mlkem768.EncapsulateDerand(pkM, eseed[:32])
 	if err != nil {
 		return nil, nil, err
 	}
-	ssM, ctM := ek.Encapsulate()
 
-	ss := combiner(ssM, ssX, ctX, pkX)
-	ct := append(ctM, ctX...)
-	return ct, ss, nil
+	ct := new([CiphertextSize]byte)
+	ss := new([SharedKeySize]byte)
+	if err := ek.encapsulate(ct, ss, ephemeralKey, ssM, ctM); err != nil {
+		return nil, nil, err
+	}
+	return ct[:], ss[:], nil
 }
 
 // Decapsulate generates a shared key from a ciphertext and a decapsulation key.
 // If the ciphertext is not valid, Decapsulate returns an error.
 //
+// Only ciphertexts of the wrong length are rejected. Invalid ML-KEM-768
+// ciphertexts are implicitly rejected, producing a pseudorandom shared key. If
+// the X25519 ciphertext is a low-order point, decapsulation proceeds with an
+// all-zero X25519 shared secret, as the draft doesn't check for this case and
+// the combiner binds the X25519 ciphertext and public key.
+//
 // The shared key must be kept secret.
 func Decapsulate(dk *DecapsulationKey, ciphertext []byte) (sharedKey []byte, err error) {
+	ss := new([SharedKeySize]byte)
+	if err := DecapsulateTo(ss, dk, ciphertext); err != nil {
+		return nil, err
+	}
+	return ss[:], nil
+}
+
+// DecapsulateTo works like [Decapsulate] but writes the shared key to the
+// provided array.
+//
+// Note that the crypto/ecdh and crypto/mlkem packages might still allocate
+// internally.
+func DecapsulateTo(sharedKey *[SharedKeySize]byte, dk *DecapsulationKey, ciphertext []byte) error {
+	if dk.destroyed {
+		return ErrKeyDestroyed
+	}
 	if len(ciphertext) != CiphertextSize {
-		return nil, errors.New("xwing: invalid ciphertext length")
+		return ErrInvalidCiphertextLength
 	}
 
 	ctM := ciphertext[:mlkem.CiphertextSize768]
@@ -153,18 +373,20 @@ func Decapsulate(dk *DecapsulationKey, ciphertext []byte) (sharedKey []byte, err
 
 	ssM, err := dk.skM.Decapsulate(ctM)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
 	peerKey, err := ecdh.X25519().NewPublicKey(ctX)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	ssX, err := dk.skX.ECDH(peerKey)
 	if err != nil {
-		return nil, err
+		// crypto/ecdh rejects all-zero X25519 outputs, which only happen for
+		// low-order points. Whether ctX is a low-order point is public.
+		ssX = make([]byte, 32)
 	}
 
-	ss := combiner(ssM, ssX, ctX, pkX)
-	return ss, nil
+	combiner(sharedKey, ssM, ssX, ctX, pkX)
+	return nil
 }